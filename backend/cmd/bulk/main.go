@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"matkis-assignment/backend/internal/bulk"
+	"matkis-assignment/backend/internal/config"
+	"matkis-assignment/backend/internal/database"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+const usage = `Usage:
+  bulk import [-format csv|ndjson] [file]   import users (stdin if no file)
+  bulk export [-format csv|ndjson] [file]   export the leaderboard (stdout if no file)
+
+The format defaults to the file extension, or csv.`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	if command != "import" && command != "export" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	formatFlag := flags.String("format", "", "file format: csv or ndjson")
+	flags.Parse(os.Args[2:])
+	path := flags.Arg(0)
+
+	format, err := fileFormat(*formatFlag, path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize PostgreSQL
+	db, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer db.Close()
+
+	// Initialize Redis
+	redisClient, err := database.NewRedisClient(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redisClient.Close()
+
+	userRepo := repository.NewUserRepository(db)
+	rankService := ranking.NewRankingService(redisClient)
+	ctx := context.Background()
+
+	if command == "import" {
+		err = runImport(ctx, bulk.NewImporter(userRepo, rankService), format, path)
+	} else {
+		err = runExport(ctx, bulk.NewExporter(userRepo, rankService), format, path)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// fileFormat picks the format from the flag, then the file extension
+func fileFormat(flagValue, path string) (bulk.Format, error) {
+	if flagValue != "" {
+		return bulk.ParseFormat(flagValue)
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(path), "."); ext != "" {
+		return bulk.ParseFormat(ext)
+	}
+	return bulk.FormatCSV, nil
+}
+
+func runImport(ctx context.Context, importer *bulk.Importer, format bulk.Format, path string) error {
+	var in io.Reader = os.Stdin
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	// Line errors go to stdout as NDJSON so they can be fixed and re-imported
+	encoder := json.NewEncoder(os.Stdout)
+	summary, err := importer.Import(ctx, bufio.NewReader(in), format, func(lineErr bulk.LineError) error {
+		return encoder.Encode(lineErr)
+	})
+	log.Printf("Imported %d users, %d lines failed", summary.Imported, summary.Failed)
+	return err
+}
+
+func runExport(ctx context.Context, exporter *bulk.Exporter, format bulk.Format, path string) error {
+	var out io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer := bufio.NewWriter(out)
+	written, err := exporter.Export(ctx, writer, format)
+	if err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Exported %d users", written)
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"matkis-assignment/backend/internal/config"
 	"matkis-assignment/backend/internal/database"
+	"matkis-assignment/backend/internal/history"
 	"matkis-assignment/backend/internal/models"
 	"matkis-assignment/backend/internal/ranking"
 	"matkis-assignment/backend/internal/repository"
@@ -37,6 +38,7 @@ func main() {
 	// Initialize services
 	userRepo := repository.NewUserRepository(db)
 	rankService := ranking.NewRankingService(redisClient)
+	historyService := history.NewService(redisClient, repository.NewHistoryRepository(db), rankService)
 
 	ctx := context.Background()
 	rand.Seed(time.Now().UnixNano())
@@ -54,6 +56,8 @@ func main() {
 		"nair", "iyer", "menon", "nair", "krishnan", "raman", "sundaram",
 	}
 
+	regions := []string{"IN", "US", "GB", "DE", "BR", "JP", "SG", "AU"}
+
 	// Generate 10,000+ users
 	numUsers := 10000
 	log.Printf("Generating %d users...", numUsers)
@@ -108,6 +112,7 @@ func main() {
 			users = append(users, &models.User{
 				Username: username,
 				Rating:   rating,
+				Region:   regions[rand.Intn(len(regions))],
 			})
 		}
 
@@ -118,8 +123,13 @@ func main() {
 				continue
 			}
 
+			if err := rankService.SetUserRegion(ctx, user.ID, user.Region); err != nil {
+				log.Printf("Warning: Failed to set region for user %s: %v", user.Username, err)
+				continue
+			}
+
 			// Update Redis leaderboard
-			if err := rankService.UpdateUserRating(ctx, user.ID, user.Rating); err != nil {
+			if err := rankService.UpdateUserRating(ctx, user.ID, user.Rating, user.Version); err != nil {
 				log.Printf("Warning: Failed to update leaderboard for user %s: %v", user.Username, err)
 				continue
 			}
@@ -133,5 +143,10 @@ func main() {
 	}
 
 	log.Printf("Successfully created %d users", usersCreated)
+
+	// Baseline snapshot so historical queries don't replay the whole seed
+	if err := historyService.TakeSnapshot(ctx); err != nil {
+		log.Printf("Warning: Failed to take leaderboard snapshot: %v", err)
+	}
 	log.Println("Seeding completed!")
 }
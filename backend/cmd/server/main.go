@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"matkis-assignment/backend/internal/anticheat"
+	"matkis-assignment/backend/internal/api"
+	"matkis-assignment/backend/internal/changes"
+	"matkis-assignment/backend/internal/config"
+	"matkis-assignment/backend/internal/database"
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/grpcapi"
+	"matkis-assignment/backend/internal/history"
+	"matkis-assignment/backend/internal/idempotency"
+	"matkis-assignment/backend/internal/matchmaking"
+	"matkis-assignment/backend/internal/rankevents"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/rating"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/search"
+	"matkis-assignment/backend/internal/stats"
+	"matkis-assignment/backend/internal/team"
+	"matkis-assignment/backend/internal/tier"
+	"matkis-assignment/backend/internal/usercache"
+	"matkis-assignment/backend/internal/webhook"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize PostgreSQL
+	db, err := database.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer db.Close()
+
+	// Initialize Redis
+	redisClient, err := database.NewRedisClient(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redisClient.Close()
+
+	tierTable, err := tier.ParseTable(cfg.Tiers)
+	if err != nil {
+		log.Fatalf("Invalid tier configuration: %v", err)
+	}
+
+	teamAggregate, err := team.ParseAggregate(cfg.TeamAggregate)
+	if err != nil {
+		log.Fatalf("Invalid team configuration: %v", err)
+	}
+
+	// Initialize services
+	userRepo := repository.NewUserRepository(db)
+	friendRepo := repository.NewFriendRepository(db)
+	teamRepo := repository.NewTeamRepository(db)
+	historyRepo := repository.NewHistoryRepository(db)
+	matchRepo := repository.NewMatchRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	rankService := ranking.NewRankingService(redisClient)
+	rankService.SetFallback(repository.NewRankingFallback(db), ranking.NewBreaker(cfg.RedisBreakerFailures, cfg.RedisBreakerCooldown))
+	eventBus := events.NewBus(redisClient)
+	tierService := tier.NewService(tierTable, rankService, eventBus)
+	rankevents.NewService(rankService, eventBus)
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, cfg.WebhookMaxAttempts, cfg.WebhookTimeout)
+	eventBus.Subscribe(webhookDispatcher.HandleEvent)
+	searchService := search.NewSearchService(userRepo, rankService, tierService)
+	teamService := team.NewService(redisClient, rankService, teamAggregate, cfg.TeamTopK)
+	historyService := history.NewService(redisClient, historyRepo, rankService)
+	statsService := stats.NewService(rankService, userRepo, cfg.StatsCacheTTL)
+	matchmakingService := matchmaking.NewService(redisClient, rankService, matchRepo, cfg.RecentOpponentWindow)
+
+	var checks []anticheat.Check
+	if cfg.AntiCheatMaxDelta > 0 {
+		checks = append(checks, anticheat.MaxDelta{Limit: cfg.AntiCheatMaxDelta})
+	}
+	if cfg.AntiCheatMaxUpdates > 0 {
+		checks = append(checks, anticheat.NewRateLimit(redisClient, cfg.AntiCheatMaxUpdates, cfg.AntiCheatWindow))
+	}
+	if cfg.AntiCheatOutlierZ > 0 {
+		checks = append(checks, anticheat.NewOutlier(historyRepo, cfg.AntiCheatOutlierZ, 50, 10))
+	}
+	antiCheat := anticheat.NewService(checks...)
+	ratingService := rating.NewService(userRepo, reviewRepo, rankService, antiCheat, cfg.ProvisionalGames)
+	idempotencyStore := idempotency.NewStore(redisClient, cfg.IdempotencyTTL)
+	displayCache := usercache.NewCache(redisClient, userRepo)
+	changeFeed := changes.NewFeed(redisClient)
+
+	// Background jobs
+	go historyService.RunSnapshots(context.Background(), cfg.SnapshotInterval)
+	go historyService.RunDailyRankSnapshots(context.Background())
+	go changeFeed.Run(context.Background())
+	go webhookDispatcher.Run(context.Background(), cfg.WebhookPollInterval)
+
+	// Setup router
+	router := api.SetupRouter(api.Deps{
+		UserRepo:           userRepo,
+		FriendRepo:         friendRepo,
+		TeamRepo:           teamRepo,
+		MatchRepo:          matchRepo,
+		HistoryRepo:        historyRepo,
+		ReviewRepo:         reviewRepo,
+		WebhookRepo:        webhookRepo,
+		RankService:        rankService,
+		SearchService:      searchService,
+		TierService:        tierService,
+		TeamService:        teamService,
+		HistoryService:     historyService,
+		StatsService:       statsService,
+		MatchmakingService: matchmakingService,
+		RatingService:      ratingService,
+		DisplayCache:       displayCache,
+		ChangeFeed:         changeFeed,
+		WebhookDispatcher:  webhookDispatcher,
+		IdempotencyStore:   idempotencyStore,
+		AdminToken:         cfg.AdminToken,
+	})
+
+	// The gRPC API shares the services above and listens on its own port
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		log.Fatalf("Failed to listen on gRPC port: %v", err)
+	}
+	grpcServer := grpcapi.NewServer(userRepo, rankService, searchService, tierService, ratingService, displayCache, changeFeed)
+	go func() {
+		log.Printf("gRPC server starting on port %s", cfg.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}()
+
+	log.Printf("Server starting on port %s", cfg.Port)
+	if err := router.Run(":" + cfg.Port); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"matkis-assignment/backend/internal/webhook"
+)
+
+// A local receiver for trying out webhooks: it checks each delivery's
+// signature and logs the event. Register it with
+//
+//	POST /api/admin/webhooks {"url": "http://localhost:9000/", "event_types": [...]}
+//
+// and start it with the secret from the response.
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	secret := flag.String("secret", "", "webhook signing secret; signatures aren't checked if empty")
+	failRate := flag.Float64("fail-rate", 0, "fraction of deliveries to answer with a 500, to exercise retries")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum age of a delivery's timestamp")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id := r.Header.Get(webhook.HeaderID)
+		if *secret != "" {
+			err := webhook.Verify(*secret, r.Header.Get(webhook.HeaderTimestamp), body, r.Header.Get(webhook.HeaderSignature), *tolerance)
+			if err != nil {
+				log.Printf("Rejected delivery %s: %v", id, err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if rand.Float64() < *failRate {
+			log.Printf("Failing delivery %s on purpose", id)
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") != nil {
+			pretty.Write(body)
+		}
+		log.Printf("Delivery %s: %s\n%s", id, r.Header.Get(webhook.HeaderEvent), pretty.String())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Webhook receiver listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		log.Fatalf("Failed to start receiver: %v", err)
+	}
+}
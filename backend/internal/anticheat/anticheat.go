@@ -0,0 +1,149 @@
+package anticheat
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/repository"
+)
+
+// Update is a rating update about to be applied
+type Update struct {
+	UserID    int64
+	OldRating int
+	NewRating int
+}
+
+// Check inspects a rating update and returns a non-empty reason if it looks
+// suspicious
+type Check interface {
+	Check(ctx context.Context, update Update) (string, error)
+}
+
+// Service runs every configured check against a rating update
+type Service struct {
+	checks []Check
+}
+
+func NewService(checks ...Check) *Service {
+	return &Service{checks: checks}
+}
+
+// Evaluate returns the reasons an update is suspicious, or nil if it passed
+// every check
+func (s *Service) Evaluate(ctx context.Context, update Update) ([]string, error) {
+	var reasons []string
+	for _, check := range s.checks {
+		reason, err := check.Check(ctx, update)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons, nil
+}
+
+// MaxDelta flags updates that move a rating by more than Limit points
+type MaxDelta struct {
+	Limit int
+}
+
+func (m MaxDelta) Check(ctx context.Context, update Update) (string, error) {
+	delta := update.NewRating - update.OldRating
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta > m.Limit {
+		return fmt.Sprintf("rating changed by %d, more than the %d allowed per update", delta, m.Limit), nil
+	}
+	return "", nil
+}
+
+// RateLimit flags users who receive more than Max updates within Window. Every
+// checked update counts, including ones that end up held for review.
+type RateLimit struct {
+	redis  *redis.Client
+	max    int
+	window time.Duration
+}
+
+func NewRateLimit(redis *redis.Client, max int, window time.Duration) *RateLimit {
+	return &RateLimit{redis: redis, max: max, window: window}
+}
+
+func (r *RateLimit) Check(ctx context.Context, update Update) (string, error) {
+	key := "anticheat:updates:" + strconv.FormatInt(update.UserID, 10)
+	now := time.Now()
+
+	// Sliding window of update timestamps
+	pipe := r.redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-r.window).UnixNano(), 10))
+	pipe.ZAdd(ctx, key, &redis.Z{Score: float64(now.UnixNano()), Member: now.UnixNano()})
+	countCmd := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, r.window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to count recent updates: %w", err)
+	}
+
+	if count := int(countCmd.Val()); count > r.max {
+		return fmt.Sprintf("%d updates within %s, more than the %d allowed", count, r.window, r.max), nil
+	}
+	return "", nil
+}
+
+// minStdDev keeps users with very regular rating changes from being flagged
+// for a slightly larger one
+const minStdDev = 25.0
+
+// Outlier flags updates whose delta is more than Threshold standard deviations
+// away from the user's recent rating changes
+type Outlier struct {
+	historyRepo *repository.HistoryRepository
+	threshold   float64
+	samples     int
+	minSamples  int
+}
+
+func NewOutlier(historyRepo *repository.HistoryRepository, threshold float64, samples, minSamples int) *Outlier {
+	return &Outlier{
+		historyRepo: historyRepo,
+		threshold:   threshold,
+		samples:     samples,
+		minSamples:  minSamples,
+	}
+}
+
+func (o *Outlier) Check(ctx context.Context, update Update) (string, error) {
+	deltas, err := o.historyRepo.RecentDeltas(ctx, update.UserID, o.samples)
+	if err != nil {
+		return "", err
+	}
+	if len(deltas) < o.minSamples {
+		// Not enough history to say what's normal for this user
+		return "", nil
+	}
+
+	var sum float64
+	for _, d := range deltas {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(deltas))
+
+	var variance float64
+	for _, d := range deltas {
+		variance += (float64(d) - mean) * (float64(d) - mean)
+	}
+	stdDev := math.Max(math.Sqrt(variance/float64(len(deltas))), minStdDev)
+
+	delta := float64(update.NewRating - update.OldRating)
+	if z := math.Abs(delta-mean) / stdDev; z > o.threshold {
+		return fmt.Sprintf("rating change of %+d is %.1f standard deviations from the user's recent changes", int(delta), z), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,101 @@
+package anticheat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func TestMaxDelta(t *testing.T) {
+	check := MaxDelta{Limit: 300}
+	for _, tt := range []struct {
+		old, new int
+		flagged  bool
+	}{
+		{1500, 1800, false},
+		{1500, 1200, false},
+		{1500, 1801, true},
+		{1500, 1199, true},
+	} {
+		reason, err := check.Check(context.Background(), Update{UserID: 1, OldRating: tt.old, NewRating: tt.new})
+		if err != nil || (reason != "") != tt.flagged {
+			t.Errorf("%d -> %d: reason %q, %v; want flagged %v", tt.old, tt.new, reason, err, tt.flagged)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	check := NewRateLimit(client, 2, time.Minute)
+	ctx := context.Background()
+	for i, want := range []bool{false, false, true} {
+		reason, err := check.Check(ctx, Update{UserID: 1})
+		if err != nil || (reason != "") != want {
+			t.Errorf("update %d: reason %q, %v; want flagged %v", i+1, reason, err, want)
+		}
+	}
+
+	// Other users have their own window
+	if reason, _ := check.Check(ctx, Update{UserID: 2}); reason != "" {
+		t.Errorf("another user was flagged: %q", reason)
+	}
+}
+
+func TestOutlier(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	check := NewOutlier(repository.NewHistoryRepository(db), 3, 20, 3)
+
+	expect := func(deltas ...int) {
+		rows := sqlmock.NewRows([]string{"delta"})
+		for _, d := range deltas {
+			rows.AddRow(d)
+		}
+		mock.ExpectQuery("FROM rating_changes").WithArgs(1, 20).WillReturnRows(rows)
+	}
+
+	tests := []struct {
+		name    string
+		deltas  []int
+		delta   int
+		flagged bool
+	}{
+		{name: "too little history", deltas: []int{10, -10}, delta: 1000},
+		{name: "usual change", deltas: []int{20, -15, 25, -20}, delta: 30},
+		// The deviation floor keeps a steady +10 player from being flagged at +50
+		{name: "steady player", deltas: []int{10, 10, 10, 10}, delta: 50},
+		{name: "outlier", deltas: []int{20, -15, 25, -20}, delta: 400, flagged: true},
+	}
+	for _, tt := range tests {
+		expect(tt.deltas...)
+		reason, err := check.Check(context.Background(), Update{UserID: 1, OldRating: 1500, NewRating: 1500 + tt.delta})
+		if err != nil || (reason != "") != tt.flagged {
+			t.Errorf("%s: reason %q, %v; want flagged %v", tt.name, reason, err, tt.flagged)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEvaluateCollectsReasons(t *testing.T) {
+	s := NewService(MaxDelta{Limit: 100}, MaxDelta{Limit: 1000}, MaxDelta{Limit: 200})
+	reasons, err := s.Evaluate(context.Background(), Update{OldRating: 1500, NewRating: 1800})
+	if err != nil || len(reasons) != 2 {
+		t.Errorf("Evaluate = %q, %v; want two reasons", reasons, err)
+	}
+	if reasons, _ := NewService().Evaluate(context.Background(), Update{OldRating: 100, NewRating: 5000}); reasons != nil {
+		t.Errorf("no checks flagged an update: %q", reasons)
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/team"
+	"matkis-assignment/backend/internal/tier"
+	"matkis-assignment/backend/internal/usercache"
+)
+
+// AccountHandler covers the rest of a user's lifecycle: renaming, deletion
+// and data export
+type AccountHandler struct {
+	userRepo    *repository.UserRepository
+	friendRepo  *repository.FriendRepository
+	matchRepo   *repository.MatchRepository
+	historyRepo *repository.HistoryRepository
+	reviewRepo  *repository.ReviewRepository
+	rankService *ranking.RankingService
+	tierService *tier.Service
+	teamService *team.Service
+
+	displayCache *usercache.Cache
+}
+
+func NewAccountHandler(userRepo *repository.UserRepository, friendRepo *repository.FriendRepository, matchRepo *repository.MatchRepository, historyRepo *repository.HistoryRepository, reviewRepo *repository.ReviewRepository, rankService *ranking.RankingService, tierService *tier.Service, teamService *team.Service, displayCache *usercache.Cache) *AccountHandler {
+	return &AccountHandler{
+		userRepo:    userRepo,
+		friendRepo:  friendRepo,
+		matchRepo:   matchRepo,
+		historyRepo: historyRepo,
+		reviewRepo:  reviewRepo,
+		rankService: rankService,
+		tierService: tierService,
+		teamService: teamService,
+
+		displayCache: displayCache,
+	}
+}
+
+// RenameUser changes a user's username, keeping the old one in their history
+func (h *AccountHandler) RenameUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Username string `json:"username" binding:"required,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username cannot be blank"})
+		return
+	}
+
+	user, err := h.userRepo.Rename(c.Request.Context(), id, username)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrUsernameTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Renaming again to the same username is a no-op, so a failure here is
+	// fixed by repeating the call
+	if err := h.displayCache.Invalidate(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.rankService.Touch(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Public())
+}
+
+// DeleteUser removes a user from every leaderboard and then from PostgreSQL.
+// Redis goes first so a failure never leaves an orphaned leaderboard entry;
+// repeating the call finishes the job.
+func (h *AccountHandler) DeleteUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if _, err := h.userRepo.GetByID(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.teamService.RemoveUser(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update team leaderboard: " + err.Error()})
+		return
+	}
+	if err := h.rankService.RemoveUser(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update leaderboard: " + err.Error()})
+		return
+	}
+
+	if err := h.userRepo.Delete(c.Request.Context(), id); err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Invalidated only once the row is gone, so a concurrent read can't cache
+	// it again. A leftover entry is never shown, as the user is off every
+	// leaderboard, and expires on its own.
+	if err := h.displayCache.Invalidate(c.Request.Context(), id); err != nil {
+		log.Printf("Warning: failed to invalidate display cache for deleted user %d: %v", id, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
+}
+
+// ExportUser returns everything stored about a user as a downloadable JSON
+// document
+func (h *AccountHandler) ExportUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.userRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, err := h.buildExport(ctx, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=user-"+strconv.FormatInt(id, 10)+".json")
+	c.JSON(http.StatusOK, export)
+}
+
+func (h *AccountHandler) buildExport(ctx context.Context, user *models.User) (*models.UserExport, error) {
+	id := user.ID
+	export := &models.UserExport{ExportedAt: time.Now().UTC()}
+
+	ranks, err := h.rankService.GetRanksForUsers(ctx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	regionalRanks, err := h.rankService.GetRegionalRanksForUsers(ctx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == models.StatusShadowBanned {
+		if ranks[id], _, err = h.rankService.ShadowRank(ctx, id); err != nil {
+			return nil, err
+		}
+		if regionalRanks[id], _, err = h.rankService.RegionalShadowRank(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	tierName, err := h.tierService.TierFor(ctx, user.Rating)
+	if err != nil {
+		return nil, err
+	}
+	export.Profile = models.UserWithRank{
+		User:         user.Public(),
+		GlobalRank:   ranks[id],
+		RegionalRank: regionalRanks[id],
+		Tier:         tierName,
+	}
+
+	if export.UsernameHistory, err = h.userRepo.UsernameHistory(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.RatingHistory, err = h.historyRepo.ListChanges(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.RankHistory, err = h.historyRepo.GetRankHistory(ctx, id, time.Time{}); err != nil {
+		return nil, err
+	}
+	if export.FriendIDs, err = h.friendRepo.ListFriendIDs(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.Matches, err = h.matchRepo.ListForUser(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.Reviews, err = h.reviewRepo.ListForUser(ctx, id); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
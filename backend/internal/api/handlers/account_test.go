@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/team"
+	"matkis-assignment/backend/internal/usercache"
+)
+
+func newAccountHandler(env *testEnv) *AccountHandler {
+	return NewAccountHandler(env.userRepo,
+		repository.NewFriendRepository(env.sqlDB),
+		repository.NewMatchRepository(env.sqlDB),
+		repository.NewHistoryRepository(env.sqlDB),
+		repository.NewReviewRepository(env.sqlDB),
+		env.rankService, env.tierService,
+		team.NewService(env.redis, env.rankService, team.AggregateAverage, 0),
+		env.cache)
+}
+
+func TestDeleteUser(t *testing.T) {
+	t.Run("deleted", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.rate(t, map[int64]int{1: 1500})
+		env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(testUser(1, 1500)))
+		env.db.ExpectExec("INSERT INTO rating_changes").WillReturnResult(sqlmock.NewResult(0, 1))
+		env.db.ExpectExec("DELETE FROM users").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		w := serve(http.MethodDelete, "/users/:id", "/users/1", newAccountHandler(env).DeleteUser, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+		}
+		if _, ok, _ := env.rankService.GetRating(context.Background(), 1); ok {
+			t.Error("user is still on the leaderboard")
+		}
+		if exists, _ := env.redis.HExists(context.Background(), usercache.DisplayKey(1), "data").Result(); exists {
+			t.Error("display cache entry was not invalidated")
+		}
+	})
+
+	t.Run("delete failed", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.rate(t, map[int64]int{1: 1500})
+		env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(testUser(1, 1500)))
+		env.db.ExpectExec("INSERT INTO rating_changes").WillReturnResult(sqlmock.NewResult(0, 1))
+		env.db.ExpectExec("DELETE FROM users").WillReturnError(errors.New("connection reset"))
+
+		w := serve(http.MethodDelete, "/users/:id", "/users/1", newAccountHandler(env).DeleteUser, nil)
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", w.Code)
+		}
+		// The cache is only invalidated once the row is gone
+		if gen, _ := env.redis.HGet(context.Background(), usercache.DisplayKey(1), "gen").Result(); gen != "" {
+			t.Errorf("display cache generation = %q before the delete committed", gen)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/rating"
+	"matkis-assignment/backend/internal/repository"
+)
+
+type AdminHandler struct {
+	userRepo      *repository.UserRepository
+	reviewRepo    *repository.ReviewRepository
+	rankService   *ranking.RankingService
+	ratingService *rating.Service
+}
+
+func NewAdminHandler(userRepo *repository.UserRepository, reviewRepo *repository.ReviewRepository, rankService *ranking.RankingService, ratingService *rating.Service) *AdminHandler {
+	return &AdminHandler{
+		userRepo:      userRepo,
+		reviewRepo:    reviewRepo,
+		rankService:   rankService,
+		ratingService: ratingService,
+	}
+}
+
+// SetModeration changes a user's moderation state and moves them on or off
+// the public leaderboards to match
+func (h *AdminHandler) SetModeration(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required,oneof=active hidden banned shadow_banned"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// PostgreSQL first so a failed Redis move is retried by repeating the call
+	user, err := h.userRepo.SetStatus(c.Request.Context(), id, req.Status)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if user.Status == models.StatusActive {
+		err = h.rankService.Restore(c.Request.Context(), id, user.Provisional)
+	} else {
+		err = h.rankService.Moderate(c.Request.Context(), id)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update leaderboard: " + err.Error()})
+		return
+	}
+	// The status shows in profiles even when the user's placement is unchanged
+	if err := h.rankService.Touch(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ListModerated returns users who are hidden, banned or shadow-banned
+func (h *AdminHandler) ListModerated(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	users, err := h.userRepo.ListModerated(c.Request.Context(), limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":  page,
+		"limit": limit,
+		"data":  users,
+	})
+}
+
+// ListReviews returns rating updates waiting for a decision, oldest first
+func (h *AdminHandler) ListReviews(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	reviews, err := h.reviewRepo.ListPending(c.Request.Context(), limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":  page,
+		"limit": limit,
+		"data":  reviews,
+	})
+}
+
+// ApproveReview applies a held rating update, unless the user has been
+// banned or their rating has changed since it was held
+func (h *AdminHandler) ApproveReview(c *gin.Context) {
+	review, ok := h.resolveReview(c, models.ReviewApproved)
+	if !ok {
+		return
+	}
+
+	if _, err := h.ratingService.ApplyReview(c.Request.Context(), review); err != nil {
+		// Put the review back so it can be retried or rejected
+		if reopenErr := h.reviewRepo.Reopen(c.Request.Context(), review.ID); reopenErr != nil {
+			log.Printf("Warning: failed to reopen review %d: %v", review.ID, reopenErr)
+		}
+		switch {
+		case errors.Is(err, repository.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, rating.ErrBanned):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrVersionConflict):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// RejectReview discards a held rating update
+func (h *AdminHandler) RejectReview(c *gin.Context) {
+	review, ok := h.resolveReview(c, models.ReviewRejected)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+// resolveReview claims the pending review in the id parameter, writing the
+// error response itself if it can't
+func (h *AdminHandler) resolveReview(c *gin.Context, status string) (*models.RatingReview, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review id"})
+		return nil, false
+	}
+
+	review, err := h.reviewRepo.Resolve(c.Request.Context(), id, status)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrReviewNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrReviewResolved):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return review, true
+}
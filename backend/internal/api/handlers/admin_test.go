@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"matkis-assignment/backend/internal/anticheat"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/rating"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func TestApproveReviewChecksUser(t *testing.T) {
+	// Review 7 held user 1's update to 2500, made against version 1
+	resolved := func(env *testEnv) {
+		at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		env.db.ExpectQuery("UPDATE rating_reviews SET status").WithArgs(models.ReviewApproved, 7).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "user_id", "old_rating", "new_rating", "user_version", "reasons", "status", "created_at", "resolved_at",
+			}).AddRow(7, 1, 1500, 2500, 1, "{delta}", models.ReviewApproved, at, at))
+	}
+
+	tests := []struct {
+		name  string
+		setup func(env *testEnv)
+		want  int
+	}{
+		{
+			name: "applied",
+			setup: func(env *testEnv) {
+				env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(testUser(1, 1500)))
+				env.db.ExpectQuery("UPDATE users SET rating").WithArgs(2500, 1, 1).
+					WillReturnRows(userRows(testUser(1, 2500)))
+				env.db.ExpectExec("INSERT INTO rating_changes").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "rating changed since",
+			setup: func(env *testEnv) {
+				current := testUser(1, 1700)
+				current.Version = 2
+				env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(current))
+				env.db.ExpectQuery("UPDATE users SET rating").WithArgs(2500, 1, 1).WillReturnRows(userRows())
+				env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(current))
+				env.db.ExpectExec("UPDATE rating_reviews SET status = 'pending'").WithArgs(7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: http.StatusPreconditionFailed,
+		},
+		{
+			name: "banned since",
+			setup: func(env *testEnv) {
+				banned := testUser(1, 1500)
+				banned.Status = models.StatusBanned
+				env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(banned))
+				env.db.ExpectExec("UPDATE rating_reviews SET status = 'pending'").WithArgs(7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			resolved(env)
+			tt.setup(env)
+			reviewRepo := repository.NewReviewRepository(env.sqlDB)
+			ratingService := rating.NewService(env.userRepo, reviewRepo, env.rankService, anticheat.NewService(), 0)
+			h := NewAdminHandler(env.userRepo, reviewRepo, env.rankService, ratingService)
+
+			w := serve(http.MethodPost, "/reviews/:id/approve", "/reviews/7/approve", h.ApproveReview, nil)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/bulk"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+type BulkHandler struct {
+	importer *bulk.Importer
+	exporter *bulk.Exporter
+}
+
+func NewBulkHandler(userRepo *repository.UserRepository, rankService *ranking.RankingService) *BulkHandler {
+	return &BulkHandler{
+		importer: bulk.NewImporter(userRepo, rankService),
+		exporter: bulk.NewExporter(userRepo, rankService),
+	}
+}
+
+// requestFormat reads the format from the query string, falling back to the
+// Content-Type header
+func requestFormat(c *gin.Context, fallback string) (bulk.Format, error) {
+	value := c.Query("format")
+	if value == "" {
+		value = fallback
+	}
+	return bulk.ParseFormat(value)
+}
+
+// ImportUsers streams a CSV or NDJSON file of users into the database and the
+// leaderboards. The response is NDJSON: one line per rejected input line as
+// it is found, then a final summary line.
+func (h *BulkHandler) ImportUsers(c *gin.Context) {
+	format, err := requestFormat(c, c.ContentType())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Let the request body be read while errors are streamed back; not every
+	// writer supports it, in which case the errors are sent all the same
+	_ = http.NewResponseController(c.Writer).EnableFullDuplex()
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+
+	summary, err := h.importer.Import(c.Request.Context(), c.Request.Body, format, func(lineErr bulk.LineError) error {
+		if err := encoder.Encode(lineErr); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent, so the failure goes in the stream
+		log.Printf("Bulk import stopped after %d users: %v", summary.Imported, err)
+		encoder.Encode(gin.H{"error": err.Error(), "summary": summary})
+		return
+	}
+	encoder.Encode(gin.H{"summary": summary})
+}
+
+// ExportLeaderboard streams the global leaderboard with ranks as CSV or NDJSON
+func (h *BulkHandler) ExportLeaderboard(c *gin.Context) {
+	format, err := requestFormat(c, string(bulk.FormatCSV))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", "attachment; filename=leaderboard."+string(format))
+	c.Status(http.StatusOK)
+
+	if _, err := h.exporter.Export(c.Request.Context(), flushWriter{c.Writer}, format); err != nil {
+		// Headers are already sent; a truncated file is all the client can see
+		log.Printf("Leaderboard export failed: %v", err)
+	}
+}
+
+// flushWriter flushes after every write so pages reach the client as they
+// are produced
+type flushWriter struct {
+	w gin.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.w.Flush()
+	return n, err
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+// boardTokenOf extracts the board version token from an entity tag; list
+// tags are just the token, profile tags prefix it with the user version
+func boardTokenOf(tag string) string {
+	tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "W/"), `"`)
+	if i := strings.LastIndexByte(tag, '-'); i >= 0 {
+		tag = tag[i+1:]
+	}
+	return tag
+}
+
+// checkNotModified revalidates a GET against the board version, which every
+// change to the leaderboards or to displayed user data bumps. It sets
+// Last-Modified and, if an If-None-Match tag carries the current version,
+// answers 304 and returns done. Otherwise it returns the version token for
+// the response's ETag, or "" if Redis couldn't be read, in which case the
+// response goes out without validators.
+func checkNotModified(c *gin.Context, rankService *ranking.RankingService) (token string, done bool) {
+	if !rankService.Healthy() {
+		return "", false
+	}
+	version, err := rankService.GetBoardVersion(c.Request.Context())
+	if err != nil {
+		return "", false
+	}
+	token = version.Token()
+
+	c.Header("Cache-Control", "no-cache")
+	if !version.Modified.IsZero() {
+		c.Header("Last-Modified", version.Modified.UTC().Format(http.TimeFormat))
+	}
+
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		if boardTokenOf(tag) == token {
+			c.Header("ETag", strings.TrimSpace(tag))
+			c.AbortWithStatus(http.StatusNotModified)
+			return token, true
+		}
+	}
+	return token, false
+}
+
+// setBoardETag tags a list response with the board version token
+func setBoardETag(c *gin.Context, token string) {
+	if token != "" {
+		c.Header("ETag", `"`+token+`"`)
+	}
+}
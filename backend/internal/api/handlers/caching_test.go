@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBoardTokenOf(t *testing.T) {
+	tests := map[string]string{
+		`"v3.1700000000000"`:     "v3.1700000000000",
+		` W/"v3.1700000000000" `: "v3.1700000000000",
+		`"7-v3.1700000000000"`:   "v3.1700000000000",
+		`W/"7-v3.1700000000000"`: "v3.1700000000000",
+		`*`:                      "*",
+	}
+	for tag, want := range tests {
+		if got := boardTokenOf(tag); got != want {
+			t.Errorf("boardTokenOf(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestCheckNotModified(t *testing.T) {
+	env := newTestEnv(t)
+	env.rate(t, map[int64]int{1: 1500})
+
+	calls := 0
+	handler := func(c *gin.Context) {
+		token, done := checkNotModified(c, env.rankService)
+		if done {
+			return
+		}
+		calls++
+		setBoardETag(c, token)
+		c.JSON(http.StatusOK, gin.H{})
+	}
+	get := func(ifNoneMatch string) (int, string) {
+		header := http.Header{}
+		if ifNoneMatch != "" {
+			header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := serve(http.MethodGet, "/leaderboard", "/leaderboard", handler, header)
+		return w.Code, w.Header().Get("ETag")
+	}
+
+	code, etag := get("")
+	if code != http.StatusOK || etag == "" {
+		t.Fatalf("first request = %d with ETag %q, want 200 with an ETag", code, etag)
+	}
+
+	// A profile tag carries the same board token behind the user version
+	profileTag := `W/"4-` + boardTokenOf(etag) + `"`
+	for _, tag := range []string{etag, `"stale", ` + etag, profileTag} {
+		if code, got := get(tag); code != http.StatusNotModified || got == "" {
+			t.Errorf("If-None-Match %s = %d with ETag %q, want 304", tag, code, got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want only for the first request", calls)
+	}
+
+	// Any leaderboard change invalidates every earlier tag
+	env.rate(t, map[int64]int{2: 1600})
+	code, fresh := get(etag)
+	if code != http.StatusOK || fresh == etag {
+		t.Errorf("after a change = %d with ETag %q, want 200 with a new ETag", code, fresh)
+	}
+
+	if err := env.rankService.Touch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := get(fresh); code != http.StatusOK {
+		t.Errorf("after Touch = %d, want 200", code)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/changes"
+)
+
+// maxPollTimeout caps how long a change request may wait for events
+const maxPollTimeout = 60
+
+type ChangeHandler struct {
+	feed *changes.Feed
+}
+
+func NewChangeHandler(feed *changes.Feed) *ChangeHandler {
+	return &ChangeHandler{
+		feed: feed,
+	}
+}
+
+// GetChanges returns global leaderboard changes after version since, waiting
+// up to timeout seconds for the first one. Without since it returns the
+// current version to follow the feed from.
+func (h *ChangeHandler) GetChanges(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	timeout, err := strconv.Atoi(c.DefaultQuery("timeout", "30"))
+	if err != nil || timeout < 0 || timeout > maxPollTimeout {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be between 0 and " + strconv.Itoa(maxPollTimeout) + " seconds"})
+		return
+	}
+
+	sinceParam := c.Query("since")
+	if sinceParam == "" {
+		current, err := h.feed.Current(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"events": []changes.Event{}, "next": current})
+		return
+	}
+	since, err := strconv.ParseInt(sinceParam, 10, 64)
+	if err != nil || since < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since"})
+		return
+	}
+
+	events, err := h.feed.Wait(c.Request.Context(), since, limit, time.Duration(timeout)*time.Second)
+	if err != nil {
+		if errors.Is(err, changes.ErrTrimmed) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := since
+	if len(events) > 0 {
+		next = events[len(events)-1].Seq
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"next":   next,
+	})
+}
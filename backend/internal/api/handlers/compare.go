@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/tier"
+)
+
+type CompareHandler struct {
+	userRepo    *repository.UserRepository
+	matchRepo   *repository.MatchRepository
+	rankService *ranking.RankingService
+	tierService *tier.Service
+}
+
+func NewCompareHandler(userRepo *repository.UserRepository, matchRepo *repository.MatchRepository, rankService *ranking.RankingService, tierService *tier.Service) *CompareHandler {
+	return &CompareHandler{
+		userRepo:    userRepo,
+		matchRepo:   matchRepo,
+		rankService: rankService,
+		tierService: tierService,
+	}
+}
+
+// Compare returns two players side by side with the rating gap between them
+func (h *CompareHandler) Compare(c *gin.Context) {
+	a, errA := strconv.ParseInt(c.Query("a"), 10, 64)
+	b, errB := strconv.ParseInt(c.Query("b"), 10, 64)
+	if errA != nil || errB != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameters 'a' and 'b' must be user ids"})
+		return
+	}
+	if a == b {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot compare a user with themselves"})
+		return
+	}
+
+	ids := []int64{a, b}
+	users, err := h.userRepo.GetByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(users) != 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	ranks, err := h.rankService.GetRanksForUsers(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tiers, err := h.tierService.Resolver(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// GetByIDs keeps the requested order, so users[0] is a and users[1] is b.
+	// As on their profile, a shadow-banned player shows the rank they would have.
+	profiles := make([]models.UserWithRank, len(users))
+	for i, user := range users {
+		if user.Status == models.StatusShadowBanned {
+			if ranks[user.ID], _, err = h.rankService.ShadowRank(c.Request.Context(), user.ID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		profiles[i] = models.UserWithRank{
+			User:       user.Public(),
+			GlobalRank: ranks[user.ID],
+			Tier:       tiers.TierFor(user.Rating),
+		}
+	}
+
+	between, err := h.rankService.CountBetween(c.Request.Context(), users[0].Rating, users[1].Rating)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := h.matchRepo.HeadToHead(c.Request.Context(), a, b)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"a":               profiles[0],
+		"b":               profiles[1],
+		"rating_gap":      users[0].Rating - users[1].Rating,
+		"players_between": between,
+		"head_to_head":    record,
+	})
+}
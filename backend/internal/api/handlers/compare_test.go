@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func TestCompare(t *testing.T) {
+	env := newTestEnv(t)
+	env.rate(t, map[int64]int{1: 2400, 2: 2000, 3: 1800, 4: 1800, 5: 1500})
+	h := NewCompareHandler(env.userRepo, repository.NewMatchRepository(env.sqlDB), env.rankService, env.tierService)
+
+	env.db.ExpectQuery("FROM users").WillReturnRows(userRows(testUser(1, 2400), testUser(5, 1500)))
+	env.db.ExpectQuery("FROM matches").WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"matches", "a_wins", "b_wins", "draws"}).AddRow(3, 2, 0, 1))
+
+	w := serve(http.MethodGet, "/compare", "/compare?a=1&b=5", h.Compare, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		A, B struct {
+			ID         int64  `json:"id"`
+			GlobalRank int    `json:"global_rank"`
+			Tier       string `json:"tier"`
+		}
+		RatingGap      int `json:"rating_gap"`
+		PlayersBetween int `json:"players_between"`
+		HeadToHead     struct {
+			Matches int `json:"matches"`
+			AWins   int `json:"a_wins"`
+		} `json:"head_to_head"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.A.ID != 1 || body.A.GlobalRank != 1 || body.A.Tier != "Gold" {
+		t.Errorf("a = %+v, want user 1 ranked 1 in Gold", body.A)
+	}
+	if body.B.ID != 5 || body.B.GlobalRank != 5 || body.B.Tier != "Silver" {
+		t.Errorf("b = %+v, want user 5 ranked 5 in Silver", body.B)
+	}
+	if body.RatingGap != 900 || body.PlayersBetween != 3 {
+		t.Errorf("gap, between = %d, %d; want 900, 3", body.RatingGap, body.PlayersBetween)
+	}
+	if body.HeadToHead.Matches != 3 || body.HeadToHead.AWins != 2 {
+		t.Errorf("head to head = %+v, want 3 matches, 2 wins for a", body.HeadToHead)
+	}
+}
+
+func TestCompareErrors(t *testing.T) {
+	env := newTestEnv(t)
+	h := NewCompareHandler(env.userRepo, repository.NewMatchRepository(env.sqlDB), env.rankService, env.tierService)
+
+	for _, query := range []string{"a=1", "a=x&b=2", "a=1&b=1"} {
+		if w := serve(http.MethodGet, "/compare", "/compare?"+query, h.Compare, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+
+	env.db.ExpectQuery("FROM users").WillReturnRows(userRows(testUser(1, 2400)))
+	if w := serve(http.MethodGet, "/compare", "/compare?a=1&b=2", h.Compare, nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing user: status = %d, want 404", w.Code)
+	}
+}
+
+func TestCompareHidesShadowBan(t *testing.T) {
+	env := newTestEnv(t)
+	env.rate(t, map[int64]int{1: 2400, 2: 2000, 3: 1800})
+	env.db.ExpectExec("INSERT INTO rating_changes").WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := env.rankService.Moderate(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	h := NewCompareHandler(env.userRepo, repository.NewMatchRepository(env.sqlDB), env.rankService, env.tierService)
+
+	banned := testUser(1, 2400)
+	banned.Status = models.StatusShadowBanned
+	env.db.ExpectQuery("FROM users").WillReturnRows(userRows(banned, testUser(3, 1800)))
+	env.db.ExpectQuery("FROM matches").
+		WillReturnRows(sqlmock.NewRows([]string{"matches", "a_wins", "b_wins", "draws"}).AddRow(0, 0, 0, 0))
+
+	w := serve(http.MethodGet, "/compare", "/compare?a=1&b=3", h.Compare, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), models.StatusShadowBanned) {
+		t.Errorf("response gives the shadow ban away: %s", w.Body)
+	}
+	var body struct {
+		A models.UserWithRank `json:"a"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.A.Status != models.StatusActive || body.A.GlobalRank != 1 {
+		t.Errorf("a = %+v, want active and ranked 1", body.A)
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/tier"
+)
+
+type FriendHandler struct {
+	userRepo    *repository.UserRepository
+	friendRepo  *repository.FriendRepository
+	rankService *ranking.RankingService
+	tierService *tier.Service
+}
+
+func NewFriendHandler(userRepo *repository.UserRepository, friendRepo *repository.FriendRepository, rankService *ranking.RankingService, tierService *tier.Service) *FriendHandler {
+	return &FriendHandler{
+		userRepo:    userRepo,
+		friendRepo:  friendRepo,
+		rankService: rankService,
+		tierService: tierService,
+	}
+}
+
+func (h *FriendHandler) AddFriend(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		FriendID int64 `json:"friend_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.FriendID == id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "users cannot befriend themselves"})
+		return
+	}
+
+	if err := h.friendRepo.Add(c.Request.Context(), id, req.FriendID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrTooManyFriends):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "friend added successfully"})
+}
+
+func (h *FriendHandler) RemoveFriend(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	friendID, err := strconv.ParseInt(c.Param("friend_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid friend id"})
+		return
+	}
+
+	if err := h.friendRepo.Remove(c.Request.Context(), id, friendID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "friendship not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "friend removed successfully"})
+}
+
+func (h *FriendHandler) ListFriends(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	friendIDs, err := h.friendRepo.ListFriendIDs(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := h.userRepo.GetByIDs(c.Request.Context(), friendIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": models.PublicUsers(users)})
+}
+
+// GetFriendsLeaderboard ranks the user and everyone they follow against each other
+func (h *FriendHandler) GetFriendsLeaderboard(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	friendIDs, err := h.friendRepo.ListFriendIDs(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userIDs := append([]int64{id}, friendIDs...)
+
+	entries, err := h.rankService.RankAmong(c.Request.Context(), userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	globalRanks, err := h.rankService.GetRanksForUsers(c.Request.Context(), userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := h.userRepo.GetByIDs(c.Request.Context(), userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userMap := make(map[int64]*models.User)
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+
+	tiers, err := h.tierService.Resolver(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		user, exists := userMap[entry.UserID]
+		if !exists {
+			continue
+		}
+		response = append(response, gin.H{
+			"rank":        entry.Rank,
+			"global_rank": globalRanks[entry.UserID],
+			"username":    user.Username,
+			"rating":      entry.Rating,
+			"user_id":     entry.UserID,
+			"tier":        tiers.TierFor(entry.Rating),
+			"is_self":     entry.UserID == id,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
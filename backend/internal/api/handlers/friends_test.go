@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func TestListFriendsHidesShadowBan(t *testing.T) {
+	env := newTestEnv(t)
+	h := NewFriendHandler(env.userRepo, repository.NewFriendRepository(env.sqlDB), env.rankService, env.tierService)
+
+	banned := testUser(3, 1800)
+	banned.Status = models.StatusShadowBanned
+	env.db.ExpectQuery("FROM friendships").WithArgs(1, repository.MaxFriends).
+		WillReturnRows(sqlmock.NewRows([]string{"friend_id"}).AddRow(2).AddRow(3))
+	env.db.ExpectQuery("FROM users").WillReturnRows(userRows(testUser(2, 1500), banned))
+
+	w := serve(http.MethodGet, "/users/:id/friends", "/users/1/friends", h.ListFriends, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), models.StatusShadowBanned) {
+		t.Errorf("response gives the shadow ban away: %s", w.Body)
+	}
+
+	var body struct {
+		Data []models.User `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 2 || body.Data[1].ID != 3 || body.Data[1].Status != models.StatusActive {
+		t.Errorf("data = %+v, want user 3 shown as active", body.Data)
+	}
+}
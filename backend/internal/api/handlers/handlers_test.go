@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/history"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/tier"
+	"matkis-assignment/backend/internal/usercache"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testEnv holds the Redis-backed services handlers share, plus a mocked
+// PostgreSQL for the repositories
+type testEnv struct {
+	redis       *redis.Client
+	db          sqlmock.Sqlmock
+	sqlDB       *sql.DB
+	userRepo    *repository.UserRepository
+	rankService *ranking.RankingService
+	tierService *tier.Service
+	bus         *events.Bus
+	history     *history.Service
+	cache       *usercache.Cache
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	rankService := ranking.NewRankingService(client)
+	bus := events.NewBus(client)
+	return &testEnv{
+		redis:       client,
+		db:          mock,
+		sqlDB:       db,
+		userRepo:    repository.NewUserRepository(db),
+		rankService: rankService,
+		tierService: tier.NewService(tier.DefaultTable, rankService, bus),
+		bus:         bus,
+		history:     history.NewService(client, repository.NewHistoryRepository(db), rankService),
+		cache:       usercache.NewCache(client, repository.NewUserRepository(db)),
+	}
+}
+
+// rate puts users on the leaderboard and in the display cache, named user<ID>
+func (env *testEnv) rate(t *testing.T, ratings map[int64]int) {
+	t.Helper()
+	ctx := context.Background()
+	for userID, rating := range ratings {
+		// The history service logs every change
+		env.db.ExpectExec("INSERT INTO rating_changes").WillReturnResult(sqlmock.NewResult(0, 1))
+		if err := env.rankService.UpdateUserRating(ctx, userID, rating, 0); err != nil {
+			t.Fatal(err)
+		}
+		display, _ := json.Marshal(usercache.Display{Username: fmt.Sprintf("user%d", userID)})
+		if err := env.redis.HSet(ctx, usercache.DisplayKey(userID), "data", display).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// serve routes a single request to handler and records the response
+func serve(method, pattern, target string, handler gin.HandlerFunc, header http.Header) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, pattern, handler)
+	req := httptest.NewRequest(method, target, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// userRows builds the rows a user query returns, in userColumns order
+func userRows(users ...*models.User) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "rating", "region", "games_played", "provisional", "status", "version", "created_at", "updated_at",
+	})
+	for _, user := range users {
+		rows.AddRow(user.ID, user.Username, user.Rating, user.Region, user.GamesPlayed, user.Provisional,
+			user.Status, user.Version, user.CreatedAt, user.UpdatedAt)
+	}
+	return rows
+}
+
+// testUser is an active, ranked user named user<ID>
+func testUser(id int64, rating int) *models.User {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &models.User{
+		ID: id, Username: fmt.Sprintf("user%d", id), Rating: rating, Region: "DE",
+		Status: models.StatusActive, Version: 1, CreatedAt: at, UpdatedAt: at,
+	}
+}
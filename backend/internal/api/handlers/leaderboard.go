@@ -1,27 +1,58 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/history"
 	"matkis-assignment/backend/internal/models"
 	"matkis-assignment/backend/internal/ranking"
 	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/tier"
+	"matkis-assignment/backend/internal/usercache"
 )
 
 type LeaderboardHandler struct {
-	userRepo    *repository.UserRepository
-	rankService *ranking.RankingService
+	userRepo       *repository.UserRepository
+	rankService    *ranking.RankingService
+	tierService    *tier.Service
+	historyService *history.Service
+	displayCache   *usercache.Cache
 }
 
-func NewLeaderboardHandler(userRepo *repository.UserRepository, rankService *ranking.RankingService) *LeaderboardHandler {
+func NewLeaderboardHandler(userRepo *repository.UserRepository, rankService *ranking.RankingService, tierService *tier.Service, historyService *history.Service, displayCache *usercache.Cache) *LeaderboardHandler {
 	return &LeaderboardHandler{
-		userRepo:    userRepo,
-		rankService: rankService,
+		userRepo:       userRepo,
+		rankService:    rankService,
+		tierService:    tierService,
+		historyService: historyService,
+		displayCache:   displayCache,
 	}
 }
 
+// parseAsOf parses an RFC 3339 timestamp or Unix seconds
+func parseAsOf(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("as_of must be an RFC 3339 timestamp or Unix seconds")
+	}
+	return t.UTC(), nil
+}
+
+// maxRankSpan caps how many entries a from_rank/to_rank query can return
+const maxRankSpan = 1000
+
+// GetLeaderboard serves the leaderboard in page mode (page/limit) or rank
+// range mode (from_rank/to_rank), optionally filtered by min_rating/max_rating,
+// tier or region, or reconstructed at a past moment with as_of
 func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
@@ -35,42 +66,223 @@ func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	// Get leaderboard entries from Redis (sorted by rating)
-	entries, err := h.rankService.GetLeaderboard(c.Request.Context(), limit, offset)
+	result := gin.H{}
+
+	// Rank range mode: positions from_rank..to_rank (1-based, inclusive)
+	fromParam, toParam := c.Query("from_rank"), c.Query("to_rank")
+	rankRange := fromParam != "" || toParam != ""
+	if rankRange {
+		fromRank, err1 := strconv.Atoi(fromParam)
+		toRank, err2 := strconv.Atoi(toParam)
+		if err1 != nil || err2 != nil || fromRank < 1 || toRank < fromRank {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from_rank and to_rank must satisfy 1 <= from_rank <= to_rank"})
+			return
+		}
+		if toRank-fromRank+1 > maxRankSpan {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rank range cannot exceed " + strconv.Itoa(maxRankSpan) + " entries"})
+			return
+		}
+		offset = fromRank - 1
+		limit = toRank - fromRank + 1
+		result["from_rank"] = fromRank
+		result["to_rank"] = toRank
+	} else {
+		result["page"] = page
+		result["limit"] = limit
+	}
+
+	tiers, err := h.tierService.Resolver(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if len(entries) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"data": []models.LeaderboardEntry{},
-			"page": page,
-			"limit": limit,
-		})
+	region, ok := ranking.ParseRegion(c.Query("region"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "region must be a two-letter country code"})
 		return
 	}
 
-	// Get user IDs from Redis entries
-	userIDs := make([]int64, len(entries))
-	for i, entry := range entries {
-		userIDs[i] = entry.UserID
+	// Rating range mode: min_rating/max_rating, narrowed further by tier
+	minRating, maxRating := 100, 5000
+	ratingFilter := false
+	if value := c.Query("min_rating"); value != "" {
+		if minRating, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_rating"})
+			return
+		}
+		ratingFilter = true
+	}
+	if value := c.Query("max_rating"); value != "" {
+		if maxRating, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_rating"})
+			return
+		}
+		ratingFilter = true
+	}
+	if tierName := c.Query("tier"); tierName != "" {
+		// Per-tier leaderboard: users within the tier's rating bounds, keeping
+		// their global (or regional) ranks
+		tierMin, tierMax, ok := tiers.Bounds(tierName)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown tier"})
+			return
+		}
+		if tierMin > minRating {
+			minRating = tierMin
+		}
+		if tierMax < maxRating {
+			maxRating = tierMax
+		}
+		ratingFilter = true
+	}
+	if rankRange && ratingFilter {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from_rank/to_rank cannot be combined with tier or rating filters"})
+		return
+	}
+	if minRating > maxRating {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_rating cannot exceed max_rating"})
+		return
+	}
+
+	asOfParam := c.Query("as_of")
+	var asOf time.Time
+	if asOfParam != "" {
+		// Historical standings are reconstructed for the global board only
+		if region != "" || ratingFilter {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "as_of cannot be combined with region, tier or rating filters"})
+			return
+		}
+		if asOf, err = parseAsOf(asOfParam); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Only a valid request can be answered with 304
+	token, done := checkNotModified(c, h.rankService)
+	if done {
+		return
 	}
 
-	// Fetch user details from PostgreSQL by IDs
-	users, err := h.userRepo.GetByIDs(c.Request.Context(), userIDs)
+	var entries []ranking.LeaderboardEntry
+	if asOfParam != "" {
+		entries, err = h.historyService.GetLeaderboardAt(c.Request.Context(), asOf, limit, offset)
+		if errors.Is(err, history.ErrNoHistory) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		result["as_of"] = asOfParam
+	} else if ratingFilter {
+		entries, err = h.rankService.GetLeaderboardByRating(c.Request.Context(), region, minRating, maxRating, limit, offset)
+		result["min_rating"] = minRating
+		result["max_rating"] = maxRating
+	} else if region != "" {
+		entries, err = h.rankService.GetRegionalLeaderboard(c.Request.Context(), region, limit, offset)
+	} else {
+		// Get leaderboard entries from Redis (sorted by rating)
+		entries, err = h.rankService.GetLeaderboard(c.Request.Context(), limit, offset)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Create map of user ID to user data
-	userMap := make(map[int64]*models.User)
-	for i := range users {
-		userMap[users[i].ID] = users[i]
+	response, err := h.withUsers(c.Request.Context(), entries, tiers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result["data"] = response
+	if region != "" {
+		result["region"] = region
+	}
+	setBoardETag(c, token)
+	c.JSON(http.StatusOK, result)
+}
+
+// GetRankForRating returns the rank and percentile a hypothetical rating would have
+func (h *LeaderboardHandler) GetRankForRating(c *gin.Context) {
+	rating, err := strconv.Atoi(c.Query("rating"))
+	if err != nil || rating < 100 || rating > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rating must be between 100 and 5000"})
+		return
+	}
+
+	rank, percentile, err := h.rankService.RankForRating(c.Request.Context(), rating)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tierName, err := h.tierService.TierFor(c.Request.Context(), rating)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rating":      rating,
+		"rank":        rank,
+		"better_than": math.Round(percentile*100) / 100,
+		"tier":        tierName,
+	})
+}
+
+// moverPeriods maps the movers period parameter to days between snapshots
+var moverPeriods = map[string]int{
+	"day":   1,
+	"week":  7,
+	"month": 30,
+}
+
+// GetMovers returns the biggest climbers and fallers over a period
+func (h *LeaderboardHandler) GetMovers(c *gin.Context) {
+	period := c.DefaultQuery("period", "day")
+	days, ok := moverPeriods[period]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week or month"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	climbers, fallers, err := h.historyService.GetMovers(c.Request.Context(), days, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"period":   period,
+		"climbers": climbers,
+		"fallers":  fallers,
+	})
+}
+
+// withUsers combines Redis ranking data with user details from PostgreSQL
+func (h *LeaderboardHandler) withUsers(ctx context.Context, entries []ranking.LeaderboardEntry, tiers *tier.Resolver) ([]models.LeaderboardEntry, error) {
+	if len(entries) == 0 {
+		return []models.LeaderboardEntry{}, nil
+	}
+
+	// Get user IDs from Redis entries
+	userIDs := make([]int64, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+	}
+
+	// Fetch usernames and regions from the display cache, falling back to
+	// PostgreSQL for users not cached yet
+	userMap, err := h.displayCache.Get(ctx, userIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	// Combine Redis ranking data with PostgreSQL user data
 	response := make([]models.LeaderboardEntry, 0, len(entries))
 	for _, entry := range entries {
 		user, exists := userMap[entry.UserID]
@@ -82,13 +294,11 @@ func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
 			Rank:     entry.Rank,
 			Username: user.Username,
 			Rating:   entry.Rating, // Use rating from Redis (source of truth for ranking)
-			UserID:   user.ID,
+			UserID:   entry.UserID,
+			Region:   user.Region,
+			Tier:     tiers.TierFor(entry.Rating),
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-		"page": page,
-		"limit": limit,
-	})
+	return response, nil
 }
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"matkis-assignment/backend/internal/models"
+)
+
+func TestGetLeaderboardAsOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		asOf  string
+		setup func(sqlmock.Sqlmock)
+		want  int
+	}{
+		{name: "invalid timestamp", asOf: "yesterday", want: http.StatusBadRequest},
+		{
+			name: "database error",
+			asOf: "1700000000",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM leaderboard_snapshots").WillReturnError(errors.New("connection refused"))
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "no history",
+			asOf: "2023-11-14T22:13:20Z",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM leaderboard_snapshots").WillReturnRows(sqlmock.NewRows([]string{"id", "taken_at"}))
+				mock.ExpectQuery("FROM rating_changes").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_rating", "new_rating", "changed_at"}))
+			},
+			want: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tt.setup != nil {
+				tt.setup(env.db)
+			}
+			h := NewLeaderboardHandler(env.userRepo, env.rankService, env.tierService, env.history, nil)
+
+			w := serve(http.MethodGet, "/leaderboard", "/leaderboard?as_of="+tt.asOf, h.GetLeaderboard, nil)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestGetRankForRating(t *testing.T) {
+	env := newTestEnv(t)
+	env.rate(t, map[int64]int{1: 2000})
+	h := NewLeaderboardHandler(env.userRepo, env.rankService, env.tierService, env.history, nil)
+
+	for _, rating := range []string{"", "abc", "99", "5001"} {
+		w := serve(http.MethodGet, "/rank", "/rank?rating="+rating, h.GetRankForRating, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("rating %q: status = %d, want 400", rating, w.Code)
+		}
+	}
+
+	w := serve(http.MethodGet, "/rank", "/rank?rating=1500", h.GetRankForRating, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Rank       int     `json:"rank"`
+		BetterThan float64 `json:"better_than"`
+		Tier       string  `json:"tier"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Rank != 2 || body.BetterThan != 0 || body.Tier != "Silver" {
+		t.Errorf("body = %+v, want rank 2, better than 0, Silver", body)
+	}
+}
+
+func TestGetLeaderboardRanges(t *testing.T) {
+	env := newTestEnv(t)
+	env.rate(t, map[int64]int{1: 2500, 2: 2000, 3: 1800, 4: 1800, 5: 1200})
+	h := NewLeaderboardHandler(env.userRepo, env.rankService, env.tierService, env.history, env.cache)
+
+	tests := []struct {
+		query   string
+		status  int
+		wantIDs []int64
+	}{
+		{query: "from_rank=2&to_rank=4", status: http.StatusOK, wantIDs: []int64{2, 4, 3}},
+		{query: "min_rating=1500&max_rating=2000", status: http.StatusOK, wantIDs: []int64{2, 4, 3}},
+		{query: "tier=Gold", status: http.StatusOK, wantIDs: []int64{1, 2}},
+		{query: "tier=Gold&max_rating=2200", status: http.StatusOK, wantIDs: []int64{2}},
+		{query: "from_rank=2", status: http.StatusBadRequest},
+		{query: "from_rank=3&to_rank=2", status: http.StatusBadRequest},
+		{query: "from_rank=1&to_rank=1001", status: http.StatusBadRequest},
+		{query: "from_rank=1&to_rank=2&min_rating=100", status: http.StatusBadRequest},
+		{query: "min_rating=2000&max_rating=1000", status: http.StatusBadRequest},
+		{query: "min_rating=abc", status: http.StatusBadRequest},
+		{query: "tier=Wood", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := serve(http.MethodGet, "/leaderboard", "/leaderboard?"+tt.query, h.GetLeaderboard, nil)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.query, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+
+		var body struct {
+			Data []models.LeaderboardEntry `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Data) != len(tt.wantIDs) {
+			t.Errorf("%s: data = %+v, want users %v", tt.query, body.Data, tt.wantIDs)
+			continue
+		}
+		for i, entry := range body.Data {
+			if entry.UserID != tt.wantIDs[i] {
+				t.Errorf("%s: data[%d] = %+v, want user %d", tt.query, i, entry, tt.wantIDs[i])
+			}
+		}
+	}
+}
+
+func TestGetLeaderboardValidatesBeforeNotModified(t *testing.T) {
+	env := newTestEnv(t)
+	env.rate(t, map[int64]int{1: 2500, 2: 2000})
+	h := NewLeaderboardHandler(env.userRepo, env.rankService, env.tierService, env.history, env.cache)
+
+	etag := serve(http.MethodGet, "/leaderboard", "/leaderboard", h.GetLeaderboard, nil).Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("leaderboard has no ETag")
+	}
+	header := http.Header{"If-None-Match": {etag}}
+	if w := serve(http.MethodGet, "/leaderboard", "/leaderboard", h.GetLeaderboard, header); w.Code != http.StatusNotModified {
+		t.Errorf("unchanged board: status = %d, want 304", w.Code)
+	}
+	for _, query := range []string{"from_rank=3&to_rank=2", "tier=Wood", "region=Germany", "as_of=yesterday"} {
+		w := serve(http.MethodGet, "/leaderboard", "/leaderboard?"+query, h.GetLeaderboard, header)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
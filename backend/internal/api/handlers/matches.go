@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/matchmaking"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/repository"
+)
+
+type MatchHandler struct {
+	matchRepo          *repository.MatchRepository
+	userRepo           *repository.UserRepository
+	matchmakingService *matchmaking.Service
+}
+
+func NewMatchHandler(matchRepo *repository.MatchRepository, userRepo *repository.UserRepository, matchmakingService *matchmaking.Service) *MatchHandler {
+	return &MatchHandler{
+		matchRepo:          matchRepo,
+		userRepo:           userRepo,
+		matchmakingService: matchmakingService,
+	}
+}
+
+// RecordMatch stores a match result. Ratings are still updated separately
+// through update-rating.
+func (h *MatchHandler) RecordMatch(c *gin.Context) {
+	var req struct {
+		PlayerA  int64  `json:"player_a" binding:"required"`
+		PlayerB  int64  `json:"player_b" binding:"required"`
+		WinnerID *int64 `json:"winner_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.PlayerA == req.PlayerB {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "players must be different"})
+		return
+	}
+	if req.WinnerID != nil && *req.WinnerID != req.PlayerA && *req.WinnerID != req.PlayerB {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "winner_id must be one of the players"})
+		return
+	}
+
+	match := &models.Match{
+		PlayerA:  req.PlayerA,
+		PlayerB:  req.PlayerB,
+		WinnerID: req.WinnerID,
+	}
+	if err := h.matchRepo.Create(c.Request.Context(), match); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, match)
+}
+
+// GetCandidates returns opponents close in rating for a user
+func (h *MatchHandler) GetCandidates(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Query("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter 'user_id' is required"})
+		return
+	}
+
+	window, err := strconv.Atoi(c.DefaultQuery("window", "100"))
+	if err != nil || window < 1 || window > matchmaking.MaxWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be between 1 and 5000"})
+		return
+	}
+
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if count < 1 || count > 50 {
+		count = 5
+	}
+
+	candidates, usedWindow, err := h.matchmakingService.FindCandidates(c.Request.Context(), userID, window, count)
+	if err != nil {
+		if errors.Is(err, matchmaking.ErrNotRanked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]int64, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.UserID
+	}
+	users, err := h.userRepo.GetByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userMap := make(map[int64]*models.User)
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+
+	response := make([]gin.H, 0, len(candidates))
+	for _, candidate := range candidates {
+		user, exists := userMap[candidate.UserID]
+		if !exists {
+			continue
+		}
+		response = append(response, gin.H{
+			"user_id":     candidate.UserID,
+			"username":    user.Username,
+			"rating":      candidate.Rating,
+			"rating_diff": candidate.RatingDiff,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   response,
+		"window": usedWindow,
+	})
+}
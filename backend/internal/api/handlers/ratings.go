@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/rating"
+)
+
+type RatingHandler struct {
+	ratingService *rating.Service
+}
+
+func NewRatingHandler(ratingService *rating.Service) *RatingHandler {
+	return &RatingHandler{
+		ratingService: ratingService,
+	}
+}
+
+// BatchUpdate applies many rating updates at once and reports a result per
+// item. In atomic mode the batch is rejected with 422 unless every item can
+// be applied.
+func (h *RatingHandler) BatchUpdate(c *gin.Context) {
+	var req struct {
+		Updates []rating.BatchItem `json:"updates" binding:"required"`
+		Atomic  bool               `json:"atomic"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Updates) == 0 || len(req.Updates) > rating.MaxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "updates must contain between 1 and " + strconv.Itoa(rating.MaxBatchSize) + " items"})
+		return
+	}
+
+	results, applied, err := h.ratingService.BatchUpdate(c.Request.Context(), req.Updates, req.Atomic)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	status := http.StatusOK
+	if !applied {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{
+		"applied": applied,
+		"atomic":  req.Atomic,
+		"counts":  counts,
+		"results": results,
+	})
+}
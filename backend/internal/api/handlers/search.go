@@ -4,16 +4,19 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/ranking"
 	"matkis-assignment/backend/internal/search"
 )
 
 type SearchHandler struct {
 	searchService *search.SearchService
+	rankService   *ranking.RankingService
 }
 
-func NewSearchHandler(searchService *search.SearchService) *SearchHandler {
+func NewSearchHandler(searchService *search.SearchService, rankService *ranking.RankingService) *SearchHandler {
 	return &SearchHandler{
 		searchService: searchService,
+		rankService:   rankService,
 	}
 }
 
@@ -24,6 +27,11 @@ func (h *SearchHandler) SearchUsers(c *gin.Context) {
 		return
 	}
 
+	token, done := checkNotModified(c, h.rankService)
+	if done {
+		return
+	}
+
 	limit := 100 // Maximum results for search
 	results, err := h.searchService.SearchUsers(c.Request.Context(), query, limit)
 	if err != nil {
@@ -35,12 +43,17 @@ func (h *SearchHandler) SearchUsers(c *gin.Context) {
 	formattedResults := make([]gin.H, len(results))
 	for i, result := range results {
 		formattedResults[i] = gin.H{
-			"global_rank": result.GlobalRank,
-			"username":    result.Username,
-			"rating":      result.Rating,
+			"global_rank":   result.GlobalRank,
+			"regional_rank": result.RegionalRank,
+			"username":      result.Username,
+			"rating":        result.Rating,
+			"region":        result.Region,
+			"tier":          result.Tier,
+			"provisional":   result.Provisional,
 		}
 	}
 
+	setBoardETag(c, token)
 	c.JSON(http.StatusOK, gin.H{
 		"data": formattedResults,
 	})
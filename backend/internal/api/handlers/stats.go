@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/stats"
+)
+
+type StatsHandler struct {
+	statsService *stats.Service
+}
+
+func NewStatsHandler(statsService *stats.Service) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+	}
+}
+
+func (h *StatsHandler) GetStats(c *gin.Context) {
+	bucketSize, err := strconv.Atoi(c.DefaultQuery("bucket_size", "500"))
+	if err != nil || bucketSize < 50 || bucketSize > stats.MaxRating {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket_size must be between 50 and 5000"})
+		return
+	}
+
+	result, err := h.statsService.GetStats(c.Request.Context(), bucketSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/team"
+)
+
+type TeamHandler struct {
+	teamRepo    *repository.TeamRepository
+	userRepo    *repository.UserRepository
+	rankService *ranking.RankingService
+	teamService *team.Service
+}
+
+func NewTeamHandler(teamRepo *repository.TeamRepository, userRepo *repository.UserRepository, rankService *ranking.RankingService, teamService *team.Service) *TeamHandler {
+	return &TeamHandler{
+		teamRepo:    teamRepo,
+		userRepo:    userRepo,
+		rankService: rankService,
+		teamService: teamService,
+	}
+}
+
+// teamError maps repository errors to HTTP responses
+func teamError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrTeamNotFound), errors.Is(err, repository.ErrUserNotFound), errors.Is(err, repository.ErrNotTeamMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrTeamNameTaken), errors.Is(err, repository.ErrAlreadyInTeam):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t := &models.Team{Name: strings.TrimSpace(req.Name)}
+	if t.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "team name is required"})
+		return
+	}
+	if err := h.teamRepo.Create(c.Request.Context(), t); err != nil {
+		teamError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, t)
+}
+
+func (h *TeamHandler) GetTeamLeaderboard(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	entries, err := h.teamService.GetLeaderboard(c.Request.Context(), limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	teamIDs := make([]int64, len(entries))
+	for i, entry := range entries {
+		teamIDs[i] = entry.TeamID
+	}
+	teams, err := h.teamRepo.GetByIDs(c.Request.Context(), teamIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]models.TeamLeaderboardEntry, 0, len(entries))
+	for _, entry := range entries {
+		t, exists := teams[entry.TeamID]
+		if !exists {
+			continue
+		}
+		response = append(response, models.TeamLeaderboardEntry{
+			Rank:   entry.Rank,
+			TeamID: t.ID,
+			Name:   t.Name,
+			Score:  entry.Score,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      response,
+		"page":      page,
+		"limit":     limit,
+		"aggregate": h.teamService.Aggregate(),
+	})
+}
+
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid team id"})
+		return
+	}
+
+	t, err := h.teamRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		teamError(c, err)
+		return
+	}
+
+	score, rank, _, err := h.teamService.GetTeamStanding(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	memberIDs, err := h.teamRepo.ListMemberIDs(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Members ranked against each other, with their global ranks alongside
+	standings, err := h.rankService.RankAmong(c.Request.Context(), memberIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	globalRanks, err := h.rankService.GetRanksForUsers(c.Request.Context(), memberIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	users, err := h.userRepo.GetByIDs(c.Request.Context(), memberIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	userMap := make(map[int64]*models.User)
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+
+	members := make([]gin.H, 0, len(standings))
+	for _, standing := range standings {
+		user, exists := userMap[standing.UserID]
+		if !exists {
+			continue
+		}
+		members = append(members, gin.H{
+			"team_rank":   standing.Rank,
+			"global_rank": globalRanks[standing.UserID],
+			"username":    user.Username,
+			"rating":      standing.Rating,
+			"user_id":     user.ID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":         t.ID,
+		"name":       t.Name,
+		"created_at": t.CreatedAt,
+		"score":      score,
+		"rank":       rank,
+		"aggregate":  h.teamService.Aggregate(),
+		"members":    members,
+	})
+}
+
+func (h *TeamHandler) AddMember(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid team id"})
+		return
+	}
+
+	var req struct {
+		UserID int64 `json:"user_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.teamRepo.AddMember(c.Request.Context(), id, req.UserID); err != nil {
+		teamError(c, err)
+		return
+	}
+
+	if err := h.teamService.AddMember(c.Request.Context(), id, req.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update team leaderboard: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "member added successfully"})
+}
+
+func (h *TeamHandler) RemoveMember(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid team id"})
+		return
+	}
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.teamRepo.RemoveMember(c.Request.Context(), id, userID); err != nil {
+		teamError(c, err)
+		return
+	}
+
+	if err := h.teamService.RemoveMember(c.Request.Context(), id, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update team leaderboard: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed successfully"})
+}
@@ -1,31 +1,209 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/history"
 	"matkis-assignment/backend/internal/models"
 	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/rating"
 	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/tier"
 )
 
 type UserHandler struct {
-	userRepo    *repository.UserRepository
-	rankService *ranking.RankingService
+	userRepo       *repository.UserRepository
+	rankService    *ranking.RankingService
+	tierService    *tier.Service
+	historyService *history.Service
+	ratingService  *rating.Service
 }
 
-func NewUserHandler(userRepo *repository.UserRepository, rankService *ranking.RankingService) *UserHandler {
+func NewUserHandler(userRepo *repository.UserRepository, rankService *ranking.RankingService, tierService *tier.Service, historyService *history.Service, ratingService *rating.Service) *UserHandler {
 	return &UserHandler{
-		userRepo:    userRepo,
-		rankService: rankService,
+		userRepo:       userRepo,
+		rankService:    rankService,
+		tierService:    tierService,
+		historyService: historyService,
+		ratingService:  ratingService,
 	}
 }
 
+func (h *UserHandler) GetUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	token, done := checkNotModified(c, h.rankService)
+	if done {
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ranks, err := h.rankService.GetRanksForUsers(c.Request.Context(), []int64{id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	regionalRanks, err := h.rankService.GetRegionalRanksForUsers(c.Request.Context(), []int64{id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Shadow-banned users still see the rank they would have, and nothing
+	// in the profile gives the ban away
+	if user.Status == models.StatusShadowBanned {
+		if ranks[id], _, err = h.rankService.ShadowRank(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if regionalRanks[id], _, err = h.rankService.RegionalShadowRank(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tierName, err := h.tierService.TierFor(c.Request.Context(), user.Rating)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The user version leads the tag so it can be sent back in If-Match;
+	// the board version after it revalidates If-None-Match
+	etag := fmt.Sprintf(`"%d"`, user.Version)
+	if token != "" {
+		etag = fmt.Sprintf(`"%d-%s"`, user.Version, token)
+	}
+	c.Header("ETag", etag)
+	c.JSON(http.StatusOK, models.UserWithRank{
+		User:         user.Public(),
+		GlobalRank:   ranks[id],
+		RegionalRank: regionalRanks[id],
+		Tier:         tierName,
+	})
+}
+
+// GetUserRank returns a user's current global rank, or their rank at as_of
+func (h *UserHandler) GetUserRank(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	asOfParam := c.Query("as_of")
+	if asOfParam == "" {
+		ranks, err := h.rankService.GetRanksForUsers(c.Request.Context(), []int64{id})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		rank, ok := ranks[id]
+		if !ok {
+			// Shadow-banned users still see the rank they would have
+			user, err := h.userRepo.GetByID(c.Request.Context(), id)
+			if err == nil && user.Status == models.StatusShadowBanned {
+				rank, ok, err = h.rankService.ShadowRank(c.Request.Context(), id)
+			}
+			if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found in leaderboard"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"user_id": id, "rank": rank})
+		return
+	}
+
+	asOf, err := parseAsOf(asOfParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rank, rating, ok, err := h.historyService.GetRankAt(c.Request.Context(), id, asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user was not on the leaderboard at that time"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id": id,
+		"rank":    rank,
+		"rating":  rating,
+		"as_of":   asOf,
+	})
+}
+
+// GetRankHistory returns a user's daily rank snapshots alongside their live rank
+func (h *UserHandler) GetRankHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days < 1 || days > 365 {
+		days = 30
+	}
+
+	snapshots, err := h.historyService.GetRankHistory(c.Request.Context(), id, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ranks, err := h.rankService.GetRanksForUsers(c.Request.Context(), []int64{id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"user_id":      id,
+		"current_rank": ranks[id],
+		"data":         snapshots,
+	}
+	// Positive change means the user climbed since the last snapshot
+	if current, ok := ranks[id]; ok && len(snapshots) > 0 {
+		response["change"] = snapshots[len(snapshots)-1].Rank - current
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
 		Rating   int    `json:"rating" binding:"required,min=100,max=5000"`
+		Region   string `json:"region"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -33,18 +211,43 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
+	region, ok := ranking.ParseRegion(req.Region)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "region must be a two-letter country code"})
+		return
+	}
+
 	user := &models.User{
-		Username: req.Username,
-		Rating:   req.Rating,
+		Username:    req.Username,
+		Rating:      req.Rating,
+		Region:      region,
+		Provisional: h.ratingService.Provisional(),
+		Status:      models.StatusActive,
 	}
 
 	if err := h.userRepo.Create(c.Request.Context(), user); err != nil {
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update Redis leaderboard
-	if err := h.rankService.UpdateUserRating(c.Request.Context(), user.ID, user.Rating); err != nil {
+	// Record the region first so the rating lands in the regional set too
+	if err := h.rankService.SetUserRegion(c.Request.Context(), user.ID, user.Region); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update leaderboard: " + err.Error()})
+		return
+	}
+
+	// Update Redis leaderboard; provisional users stay off the public boards
+	var err error
+	if user.Provisional {
+		err = h.rankService.AddProvisional(c.Request.Context(), user.ID, user.Rating)
+	} else {
+		err = h.rankService.UpdateUserRating(c.Request.Context(), user.ID, user.Rating, user.Version)
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update leaderboard: " + err.Error()})
 		return
 	}
@@ -61,7 +264,8 @@ func (h *UserHandler) UpdateRating(c *gin.Context) {
 	}
 
 	var req struct {
-		Rating int `json:"rating" binding:"required,min=100,max=5000"`
+		Rating          int   `json:"rating" binding:"required,min=100,max=5000"`
+		ExpectedVersion int64 `json:"expected_version" binding:"min=0"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -69,17 +273,80 @@ func (h *UserHandler) UpdateRating(c *gin.Context) {
 		return
 	}
 
-	// Update in PostgreSQL
-	if err := h.userRepo.UpdateRating(c.Request.Context(), id, req.Rating); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	expectedVersion, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must be a user version"})
 		return
 	}
+	if expectedVersion != 0 && req.ExpectedVersion != 0 && expectedVersion != req.ExpectedVersion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match and expected_version disagree"})
+		return
+	}
+	if expectedVersion == 0 {
+		expectedVersion = req.ExpectedVersion
+	}
 
-	// Update in Redis
-	if err := h.rankService.UpdateUserRating(c.Request.Context(), id, req.Rating); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update leaderboard: " + err.Error()})
+	result, err := h.ratingService.Update(c.Request.Context(), id, req.Rating, expectedVersion)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, rating.ErrBanned):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrVersionConflict):
+			h.versionConflict(c, id, err)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Suspicious updates are held until an admin approves them
+	if result.Review != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":   "rating update held for review",
+			"review_id": result.Review.ID,
+			"reasons":   result.Review.Reasons,
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "rating updated successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "rating updated successfully",
+		"version": result.User.Version,
+	})
+}
+
+// parseIfMatch reads a user version from an If-Match header, accepting it
+// bare or as an entity tag, with or without the profile's board version
+// suffix. An empty header gives version 0.
+func parseIfMatch(value string) (int64, bool) {
+	value = strings.Trim(strings.TrimPrefix(strings.TrimSpace(value), "W/"), `"`)
+	if value == "" {
+		return 0, true
+	}
+	if i := strings.IndexByte(value, '-'); i >= 0 {
+		value = value[:i]
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// versionConflict responds 412 with the user's current rating and version so
+// the caller can retry against them
+func (h *UserHandler) versionConflict(c *gin.Context, id int64, conflict error) {
+	current, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", fmt.Sprintf(`"%d"`, current.Version))
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   conflict.Error(),
+		"rating":  current.Rating,
+		"version": current.Version,
+	})
 }
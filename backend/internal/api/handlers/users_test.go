@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/anticheat"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/rating"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		value   string
+		version int64
+		ok      bool
+	}{
+		{value: "", version: 0, ok: true},
+		{value: "7", version: 7, ok: true},
+		{value: `"7"`, version: 7, ok: true},
+		{value: `W/"7"`, version: 7, ok: true},
+		{value: `"7-v12.1700000000000"`, version: 7, ok: true},
+		{value: `"0"`, ok: false},
+		{value: `"abc"`, ok: false},
+		{value: "*", ok: false},
+	}
+	for _, tt := range tests {
+		version, ok := parseIfMatch(tt.value)
+		if version != tt.version || ok != tt.ok {
+			t.Errorf("parseIfMatch(%q) = %d, %v; want %d, %v", tt.value, version, ok, tt.version, tt.ok)
+		}
+	}
+}
+
+func newUserHandler(env *testEnv) *UserHandler {
+	ratingService := rating.NewService(env.userRepo, repository.NewReviewRepository(env.sqlDB), env.rankService, anticheat.NewService(), 0)
+	return NewUserHandler(env.userRepo, env.rankService, env.tierService, env.history, ratingService)
+}
+
+func updateRating(h *UserHandler, body, ifMatch string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/users/:id/update-rating", h.UpdateRating)
+	req := httptest.NewRequest(http.MethodPost, "/users/1/update-rating", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateRatingVersions(t *testing.T) {
+	t.Run("applied", func(t *testing.T) {
+		env := newTestEnv(t)
+		env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(testUser(1, 1500)))
+		env.db.ExpectQuery("UPDATE users SET rating").WithArgs(1600, 1, 1).
+			WillReturnRows(userRows(testUser(1, 1600)))
+		env.db.ExpectExec("INSERT INTO rating_changes").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		w := updateRating(newUserHandler(env), `{"rating":1600}`, `"1-v3.1700000000000"`)
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d, want 200: %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		env := newTestEnv(t)
+		current := testUser(1, 1550)
+		current.Version = 4
+		env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(current))
+		env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(current))
+
+		w := updateRating(newUserHandler(env), `{"rating":1600,"expected_version":3}`, "")
+		if w.Code != http.StatusPreconditionFailed {
+			t.Fatalf("status = %d, want 412: %s", w.Code, w.Body)
+		}
+		if etag := w.Header().Get("ETag"); etag != `"4"` {
+			t.Errorf("ETag = %s, want \"4\"", etag)
+		}
+		var body struct {
+			Rating  int   `json:"rating"`
+			Version int64 `json:"version"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Rating != 1550 || body.Version != 4 {
+			t.Errorf("body = %+v, want the current rating 1550 at version 4", body)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		env := newTestEnv(t)
+		h := newUserHandler(env)
+		for _, tt := range []struct{ body, ifMatch string }{
+			{`{"rating":1600}`, `"abc"`},
+			{`{"rating":1600,"expected_version":2}`, `"3"`},
+			{`{"rating":1600,"expected_version":-1}`, ""},
+			{`{"rating":50}`, ""},
+		} {
+			if w := updateRating(h, tt.body, tt.ifMatch); w.Code != http.StatusBadRequest {
+				t.Errorf("%s with If-Match %s: status = %d, want 400", tt.body, tt.ifMatch, w.Code)
+			}
+		}
+	})
+}
+
+func TestGetUserHidesShadowBan(t *testing.T) {
+	env := newTestEnv(t)
+	env.rate(t, map[int64]int{1: 2400, 2: 2000, 3: 2200})
+	ctx := context.Background()
+	for userID, region := range map[int64]string{1: "DE", 2: "DE", 3: "FR"} {
+		if err := env.rankService.SetUserRegion(ctx, userID, region); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.db.ExpectExec("INSERT INTO rating_changes").WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := env.rankService.Moderate(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	banned := testUser(2, 2000)
+	banned.Status = models.StatusShadowBanned
+	env.db.ExpectQuery("FROM users WHERE id").WillReturnRows(userRows(banned))
+
+	w := serve(http.MethodGet, "/users/:id", "/users/2", newUserHandler(env).GetUser, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body models.UserWithRank
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != models.StatusActive || body.GlobalRank != 3 || body.RegionalRank != 2 {
+		t.Errorf("body = %+v, want active, ranked 3 globally and 2 in DE", body)
+	}
+}
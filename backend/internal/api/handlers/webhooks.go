@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/webhook"
+)
+
+type WebhookHandler struct {
+	webhookRepo *repository.WebhookRepository
+	dispatcher  *webhook.Dispatcher
+}
+
+func NewWebhookHandler(webhookRepo *repository.WebhookRepository, dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo: webhookRepo,
+		dispatcher:  dispatcher,
+	}
+}
+
+// CreateWebhook subscribes a URL to event types. The response is the only
+// time the signing secret is shown.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req struct {
+		URL        string   `json:"url" binding:"required"`
+		EventTypes []string `json:"event_types" binding:"required"`
+		Active     *bool    `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := webhook.ValidateURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := webhook.ValidateEventTypes(req.EventTypes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hook := &models.Webhook{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+		Active:     req.Active == nil || *req.Active,
+	}
+	if err := h.webhookRepo.Create(c.Request.Context(), hook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.dispatcher.Refresh()
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.webhookRepo.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": hooks})
+}
+
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+// UpdateWebhook changes any of a webhook's URL, event types and active flag
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req struct {
+		URL        *string  `json:"url"`
+		EventTypes []string `json:"event_types"`
+		Active     *bool    `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	if req.URL != nil {
+		if err := webhook.ValidateURL(*req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		hook.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		if err := webhook.ValidateEventTypes(req.EventTypes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		hook.EventTypes = req.EventTypes
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+
+	if err := h.webhookRepo.Update(c.Request.Context(), hook); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.dispatcher.Refresh()
+
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+// DeleteWebhook removes a webhook along with its queued and past deliveries
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+		return
+	}
+
+	if err := h.webhookRepo.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.dispatcher.Refresh()
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestWebhook queues a ping event for the webhook, whatever it subscribes to
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	queued, err := h.dispatcher.Enqueue(c.Request.Context(), events.Event{
+		Type: events.Ping,
+		Data: map[string]interface{}{"webhook_id": hook.ID},
+	}, hook.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if queued == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": repository.ErrWebhookNotFound.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"queued": queued})
+}
+
+// ListDeliveries returns a webhook's deliveries, newest first. Use
+// ?status=dead for the dead-letter list.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && status != models.DeliveryPending && status != models.DeliveryDelivered && status != models.DeliveryDead {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, delivered or dead"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	deliveries, err := h.webhookRepo.ListDeliveries(c.Request.Context(), id, status, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":  page,
+		"limit": limit,
+		"data":  deliveries,
+	})
+}
+
+// RetryDelivery puts a dead-lettered delivery back in the queue
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery id"})
+		return
+	}
+
+	delivery, err := h.webhookRepo.Redeliver(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDeliveryNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrDeliveryNotDead):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (h *WebhookHandler) loadWebhook(c *gin.Context) (*models.Webhook, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+		return nil, false
+	}
+
+	hook, err := h.webhookRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return hook, true
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+// requireAdmin only lets through requests carrying the admin token as a bearer
+// token. Admin routes are disabled when no token is configured.
+func requireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin API is disabled"})
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// DegradedHeader marks responses built from the PostgreSQL fallback rather
+// than the Redis leaderboards
+const DegradedHeader = "X-Degraded"
+
+// markDegraded sets DegradedHeader on responses to requests whose rankings
+// were served by the fallback
+func markDegraded() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := ranking.WithDegradedFlag(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &degradedWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Next()
+	}
+}
+
+// degradedWriter adds the header just before the response is written, once
+// the handler has made its reads
+type degradedWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+func (w *degradedWriter) mark() {
+	if !w.Written() && ranking.Degraded(w.ctx) {
+		w.Header().Set(DegradedHeader, "true")
+	}
+}
+
+func (w *degradedWriter) WriteHeaderNow() {
+	w.mark()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *degradedWriter) Write(b []byte) (int, error) {
+	w.mark()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *degradedWriter) WriteString(s string) (int, error) {
+	w.mark()
+	return w.ResponseWriter.WriteString(s)
+}
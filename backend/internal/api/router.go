@@ -1,21 +1,62 @@
 package api
 
 import (
+	"expvar"
+
 	"github.com/gin-gonic/gin"
 	"matkis-assignment/backend/internal/api/handlers"
+	"matkis-assignment/backend/internal/changes"
+	"matkis-assignment/backend/internal/history"
+	"matkis-assignment/backend/internal/idempotency"
+	"matkis-assignment/backend/internal/matchmaking"
 	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/rating"
 	"matkis-assignment/backend/internal/repository"
 	"matkis-assignment/backend/internal/search"
+	"matkis-assignment/backend/internal/stats"
+	"matkis-assignment/backend/internal/team"
+	"matkis-assignment/backend/internal/tier"
+	"matkis-assignment/backend/internal/usercache"
+	"matkis-assignment/backend/internal/webhook"
 )
 
-func SetupRouter(userRepo *repository.UserRepository, rankService *ranking.RankingService, searchService *search.SearchService) *gin.Engine {
+// Deps holds everything the HTTP handlers are built from
+type Deps struct {
+	UserRepo    *repository.UserRepository
+	FriendRepo  *repository.FriendRepository
+	TeamRepo    *repository.TeamRepository
+	MatchRepo   *repository.MatchRepository
+	HistoryRepo *repository.HistoryRepository
+	ReviewRepo  *repository.ReviewRepository
+	WebhookRepo *repository.WebhookRepository
+
+	RankService        *ranking.RankingService
+	SearchService      *search.SearchService
+	TierService        *tier.Service
+	TeamService        *team.Service
+	HistoryService     *history.Service
+	StatsService       *stats.Service
+	MatchmakingService *matchmaking.Service
+	RatingService      *rating.Service
+
+	DisplayCache      *usercache.Cache
+	ChangeFeed        *changes.Feed
+	WebhookDispatcher *webhook.Dispatcher
+	IdempotencyStore  *idempotency.Store
+
+	// AdminToken guards /api/admin, /debug/vars and the account routes
+	AdminToken string
+}
+
+func SetupRouter(deps Deps) *gin.Engine {
 	router := gin.Default()
 
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key, If-None-Match, If-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, "+DegradedHeader)
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -28,19 +69,100 @@ func SetupRouter(userRepo *repository.UserRepository, rankService *ranking.Ranki
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
+		status := gin.H{"status": "ok"}
+		if !deps.RankService.Healthy() {
+			status["status"] = "degraded"
+		}
+		c.JSON(200, status)
 	})
 
+	// expvar metrics, including the user display cache hit rate
+	router.GET("/debug/vars", requireAdmin(deps.AdminToken), gin.WrapH(expvar.Handler()))
+
+	// The import is streamed, and a new webhook's response carries its
+	// signing secret; neither goes through the idempotency store
+	idempotent := deps.IdempotencyStore.Middleware("/api/admin/users/import", "/api/admin/webhooks")
+
 	api := router.Group("/api")
+	api.Use(markDegraded(), idempotent)
 	{
-		leaderboardHandler := handlers.NewLeaderboardHandler(userRepo, rankService)
-		searchHandler := handlers.NewSearchHandler(searchService)
-		userHandler := handlers.NewUserHandler(userRepo, rankService)
+		leaderboardHandler := handlers.NewLeaderboardHandler(deps.UserRepo, deps.RankService, deps.TierService, deps.HistoryService, deps.DisplayCache)
+		searchHandler := handlers.NewSearchHandler(deps.SearchService, deps.RankService)
+		userHandler := handlers.NewUserHandler(deps.UserRepo, deps.RankService, deps.TierService, deps.HistoryService, deps.RatingService)
+		friendHandler := handlers.NewFriendHandler(deps.UserRepo, deps.FriendRepo, deps.RankService, deps.TierService)
+		teamHandler := handlers.NewTeamHandler(deps.TeamRepo, deps.UserRepo, deps.RankService, deps.TeamService)
+		statsHandler := handlers.NewStatsHandler(deps.StatsService)
+		compareHandler := handlers.NewCompareHandler(deps.UserRepo, deps.MatchRepo, deps.RankService, deps.TierService)
+		matchHandler := handlers.NewMatchHandler(deps.MatchRepo, deps.UserRepo, deps.MatchmakingService)
+		accountHandler := handlers.NewAccountHandler(deps.UserRepo, deps.FriendRepo, deps.MatchRepo, deps.HistoryRepo, deps.ReviewRepo, deps.RankService, deps.TierService, deps.TeamService, deps.DisplayCache)
+		ratingHandler := handlers.NewRatingHandler(deps.RatingService)
+		adminHandler := handlers.NewAdminHandler(deps.UserRepo, deps.ReviewRepo, deps.RankService, deps.RatingService)
+		bulkHandler := handlers.NewBulkHandler(deps.UserRepo, deps.RankService)
+		changeHandler := handlers.NewChangeHandler(deps.ChangeFeed)
+		webhookHandler := handlers.NewWebhookHandler(deps.WebhookRepo, deps.WebhookDispatcher)
 
 		api.GET("/leaderboard", leaderboardHandler.GetLeaderboard)
+		api.GET("/leaderboard/movers", leaderboardHandler.GetMovers)
+		api.GET("/leaderboard/rank-for", leaderboardHandler.GetRankForRating)
+		api.GET("/leaderboard/export", bulkHandler.ExportLeaderboard)
+		api.GET("/changes", changeHandler.GetChanges)
 		api.GET("/search", searchHandler.SearchUsers)
+		api.GET("/stats", statsHandler.GetStats)
+		api.GET("/compare", compareHandler.Compare)
+		api.POST("/matches", matchHandler.RecordMatch)
+		api.GET("/matchmaking/candidates", matchHandler.GetCandidates)
 		api.POST("/users", userHandler.CreateUser)
+		api.GET("/users/:id", userHandler.GetUser)
+		api.GET("/users/:id/rank", userHandler.GetUserRank)
+		api.GET("/users/:id/rank-history", userHandler.GetRankHistory)
 		api.POST("/users/:id/update-rating", userHandler.UpdateRating)
+
+		// Custom methods use Google API style paths (/ratings:batch). Gin reads
+		// the colon as the start of a parameter, so the verb is matched here.
+		api.POST("/ratings:verb", func(c *gin.Context) {
+			switch c.Param("verb") {
+			case ":batch":
+				ratingHandler.BatchUpdate(c)
+			default:
+				c.JSON(404, gin.H{"error": "unknown method"})
+			}
+		})
+		api.GET("/users/:id/friends", friendHandler.ListFriends)
+		api.POST("/users/:id/friends", friendHandler.AddFriend)
+		api.DELETE("/users/:id/friends/:friend_id", friendHandler.RemoveFriend)
+		api.GET("/users/:id/friends/leaderboard", friendHandler.GetFriendsLeaderboard)
+
+		api.POST("/teams", teamHandler.CreateTeam)
+		api.GET("/teams/leaderboard", teamHandler.GetTeamLeaderboard)
+		api.GET("/teams/:id", teamHandler.GetTeam)
+		api.POST("/teams/:id/members", teamHandler.AddMember)
+		api.DELETE("/teams/:id/members/:user_id", teamHandler.RemoveMember)
+
+		// Admin routes authenticate before the idempotency store sees them, so
+		// a rejected request never claims or stores a key
+		admin := router.Group("/api/admin", markDegraded(), requireAdmin(deps.AdminToken), idempotent)
+
+		admin.GET("/moderation", adminHandler.ListModerated)
+		admin.PUT("/users/:id/moderation", adminHandler.SetModeration)
+		admin.GET("/reviews", adminHandler.ListReviews)
+		admin.POST("/reviews/:id/approve", adminHandler.ApproveReview)
+		admin.POST("/reviews/:id/reject", adminHandler.RejectReview)
+		admin.POST("/users/import", bulkHandler.ImportUsers)
+		admin.POST("/webhooks", webhookHandler.CreateWebhook)
+		admin.GET("/webhooks", webhookHandler.ListWebhooks)
+		admin.GET("/webhooks/:id", webhookHandler.GetWebhook)
+		admin.PATCH("/webhooks/:id", webhookHandler.UpdateWebhook)
+		admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		admin.POST("/webhooks/:id/test", webhookHandler.TestWebhook)
+		admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+		admin.POST("/webhooks/:id/deliveries/:delivery_id/retry", webhookHandler.RetryDelivery)
+
+		// Renaming, deleting and exporting an account take the admin token too;
+		// there are no user credentials to check ownership against
+		account := router.Group("/api/users/:id", markDegraded(), requireAdmin(deps.AdminToken), idempotent)
+		account.PATCH("", accountHandler.RenameUser)
+		account.DELETE("", accountHandler.DeleteUser)
+		account.GET("/export", accountHandler.ExportUser)
 	}
 
 	return router
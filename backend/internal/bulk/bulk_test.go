@@ -0,0 +1,163 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func newTestEnv(t *testing.T) (*repository.UserRepository, *ranking.RankingService, sqlmock.Sqlmock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return repository.NewUserRepository(db), ranking.NewRankingService(client), mock
+}
+
+var userColumns = []string{"id", "username", "rating", "region", "games_played", "provisional", "status", "version", "created_at", "updated_at"}
+
+func TestParseFormat(t *testing.T) {
+	tests := map[string]Format{
+		"csv":                     FormatCSV,
+		"text/csv; charset=utf-8": FormatCSV,
+		"NDJSON":                  FormatNDJSON,
+		"application/x-ndjson":    FormatNDJSON,
+		"application/jsonl":       FormatNDJSON,
+	}
+	for value, want := range tests {
+		if got, err := ParseFormat(value); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("application/json"); err == nil {
+		t.Error("ParseFormat(application/json) accepted a non-streaming format")
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	userRepo, rankService, mock := newTestEnv(t)
+	ctx := context.Background()
+
+	input := strings.Join([]string{
+		"Rating,Username,Region",
+		"1500,alice,de",
+		"50,bob,DE",
+		"1600,,US",
+		"abc,carol,US",
+		"1700,dave,Germany",
+		"1800,alice,FR",
+		"2000,erin,",
+		"1900,frank,US",
+	}, "\n")
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO users (username, rating, region, provisional)")).
+		WithArgs("alice", 1500, "DE", false, "erin", 2000, "", false, "frank", 1900, "US", false).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(1, "alice", 1500, "DE", 0, false, "active", 1, now, now).
+			AddRow(2, "erin", 2000, "", 0, false, "active", 1, now, now))
+
+	var errs []LineError
+	summary, err := NewImporter(userRepo, rankService).Import(ctx, strings.NewReader(input), FormatCSV, func(e LineError) error {
+		errs = append(errs, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Imported != 2 || summary.Failed != 6 {
+		t.Errorf("summary = %+v, want 2 imported and 6 failed", summary)
+	}
+
+	// frank lost a race for the username to an existing user
+	wantLines := []int{3, 4, 5, 6, 7, 9}
+	if len(errs) != len(wantLines) {
+		t.Fatalf("got %d line errors, want %d: %+v", len(errs), len(wantLines), errs)
+	}
+	for i, e := range errs {
+		if e.Line != wantLines[i] {
+			t.Errorf("error %d is on line %d, want %d: %+v", i, e.Line, wantLines[i], e)
+		}
+	}
+	if errs[5].Error != repository.ErrUsernameTaken.Error() {
+		t.Errorf("line 9 error = %q, want %q", errs[5].Error, repository.ErrUsernameTaken)
+	}
+
+	if rating, ok, _ := rankService.GetRating(ctx, 1); !ok || rating != 1500 {
+		t.Errorf("alice's leaderboard rating = %d, %v; want 1500", rating, ok)
+	}
+	if entries, err := rankService.GetRegionalLeaderboard(ctx, "DE", 10, 0); err != nil || len(entries) != 1 || entries[0].UserID != 1 {
+		t.Errorf("DE leaderboard = %+v, %v; want only alice", entries, err)
+	}
+}
+
+func TestImportNDJSONStopsOnCallbackError(t *testing.T) {
+	userRepo, rankService, _ := newTestEnv(t)
+
+	input := "{\"username\":\"alice\",\"rating\":1500}\nnot json\n{\"username\":\"bob\",\"rating\":1500}\n"
+	stop := context.Canceled
+	_, err := NewImporter(userRepo, rankService).Import(context.Background(), strings.NewReader(input), FormatNDJSON, func(e LineError) error {
+		if e.Line != 2 {
+			t.Errorf("error on line %d, want 2", e.Line)
+		}
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Import error = %v, want the callback's error", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	userRepo, rankService, mock := newTestEnv(t)
+	ctx := context.Background()
+
+	for id, rating := range map[int64]int{1: 1500, 2: 1800, 3: 1800, 4: 1200} {
+		if err := rankService.UpdateUserRating(ctx, id, rating, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// User 4 was deleted from PostgreSQL but is still on the board
+	now := time.Now()
+	mock.ExpectQuery("FROM users").WillReturnRows(sqlmock.NewRows(userColumns).
+		AddRow(1, "alice", 1500, "DE", 0, false, "active", 1, now, now).
+		AddRow(2, "bob", 1800, "US", 0, false, "active", 1, now, now).
+		AddRow(3, "carol", 1800, "", 0, false, "active", 1, now, now))
+
+	var out bytes.Buffer
+	written, err := NewExporter(userRepo, rankService).Export(ctx, &out, FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 3 {
+		t.Errorf("wrote %d rows, want 3", written)
+	}
+
+	want := "rank,user_id,username,rating,region\n" +
+		"1,3,carol,1800,\n" +
+		"1,2,bob,1800,US\n" +
+		"3,1,alice,1500,DE\n"
+	if out.String() != want {
+		t.Errorf("export =\n%s\nwant\n%s", out.String(), want)
+	}
+}
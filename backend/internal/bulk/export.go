@@ -0,0 +1,124 @@
+package bulk
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+// exportPage is how many leaderboard entries are read per Redis call
+const exportPage = 1000
+
+// ExportRow is one user in a leaderboard export
+type ExportRow struct {
+	Rank     int    `json:"rank"`
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
+	Region   string `json:"region"`
+}
+
+var exportHeader = []string{"rank", "user_id", "username", "rating", "region"}
+
+// Exporter streams the global leaderboard, with ranks, as CSV or NDJSON
+type Exporter struct {
+	userRepo    *repository.UserRepository
+	rankService *ranking.RankingService
+}
+
+func NewExporter(userRepo *repository.UserRepository, rankService *ranking.RankingService) *Exporter {
+	return &Exporter{
+		userRepo:    userRepo,
+		rankService: rankService,
+	}
+}
+
+// Export writes the leaderboard to w a page at a time, so memory use doesn't
+// grow with the number of users. Entries whose user no longer exists in
+// PostgreSQL are left out. It returns the number of rows written.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, format Format) (int, error) {
+	write, flush := e.newRowWriter(w, format)
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for offset := 0; ; offset += exportPage {
+		entries, err := e.rankService.GetLeaderboard(ctx, exportPage, offset)
+		if err != nil {
+			return written, err
+		}
+		if len(entries) == 0 {
+			return written, nil
+		}
+
+		ids := make([]int64, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.UserID
+		}
+		users, err := e.userRepo.GetByIDs(ctx, ids)
+		if err != nil {
+			return written, err
+		}
+		byID := make(map[int64]int, len(users))
+		for i, user := range users {
+			byID[user.ID] = i
+		}
+
+		for _, entry := range entries {
+			i, ok := byID[entry.UserID]
+			if !ok {
+				continue
+			}
+			row := ExportRow{
+				Rank:     entry.Rank,
+				UserID:   entry.UserID,
+				Username: users[i].Username,
+				Rating:   entry.Rating,
+				Region:   users[i].Region,
+			}
+			if err := write(row); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if err := flush(); err != nil {
+			return written, err
+		}
+
+		if len(entries) < exportPage {
+			return written, nil
+		}
+	}
+}
+
+// newRowWriter returns functions to write a row and to flush buffered rows
+// to w. For CSV the header row is buffered straight away.
+func (e *Exporter) newRowWriter(w io.Writer, format Format) (func(ExportRow) error, func() error) {
+	if format == FormatCSV {
+		writer := csv.NewWriter(w)
+		writer.Write(exportHeader)
+		write := func(row ExportRow) error {
+			return writer.Write([]string{
+				strconv.Itoa(row.Rank),
+				strconv.FormatInt(row.UserID, 10),
+				row.Username,
+				strconv.Itoa(row.Rating),
+				row.Region,
+			})
+		}
+		flush := func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+		return write, flush
+	}
+
+	encoder := json.NewEncoder(w)
+	return func(row ExportRow) error { return encoder.Encode(row) }, func() error { return nil }
+}
@@ -0,0 +1,160 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format is a bulk file format
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// ParseFormat accepts a format name or a matching content type
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(strings.SplitN(value, ";", 2)[0])) {
+	case "csv", "text/csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl", "application/x-ndjson", "application/jsonl":
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("unknown format %q: expected csv or ndjson", value)
+}
+
+// ContentType returns the MIME type for the format
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// Record is one user in an import file
+type Record struct {
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
+	Region   string `json:"region"`
+}
+
+// recordReader yields records one line at a time. line is the 1-based line
+// number in the file; err is set for a line that can't be parsed, and
+// io.EOF ends the input.
+type recordReader interface {
+	Next() (line int, record Record, err error)
+}
+
+// lineError is a problem with one line that doesn't stop the import
+type lineError struct {
+	err error
+}
+
+func (e *lineError) Error() string { return e.err.Error() }
+
+func newRecordReader(r io.Reader, format Format) (recordReader, error) {
+	if format == FormatCSV {
+		return newCSVReader(r)
+	}
+	return &ndjsonReader{scanner: newLineScanner(r)}, nil
+}
+
+// maxLineLength caps a single NDJSON line
+const maxLineLength = 64 * 1024
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineLength)
+	return scanner
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (n *ndjsonReader) Next() (int, Record, error) {
+	for n.scanner.Scan() {
+		n.line++
+		text := strings.TrimSpace(n.scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return n.line, Record{}, &lineError{err: fmt.Errorf("invalid JSON: %v", err)}
+		}
+		return n.line, record, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return n.line + 1, Record{}, err
+	}
+	return n.line, Record{}, io.EOF
+}
+
+// csvReader reads files with a username,rating[,region] header row. Columns
+// may come in any order.
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("CSV file is empty")
+		}
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"username", "rating"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %q column", required)
+		}
+	}
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvReader) field(row []string, name string) string {
+	if i, ok := c.columns[name]; ok && i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+func (c *csvReader) Next() (int, Record, error) {
+	row, err := c.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.Line, Record{}, &lineError{err: parseErr.Err}
+		}
+		return 0, Record{}, err
+	}
+	line, _ := c.reader.FieldPos(0)
+
+	record := Record{
+		Username: c.field(row, "username"),
+		Region:   c.field(row, "region"),
+	}
+	rating := strings.TrimSpace(c.field(row, "rating"))
+	if record.Rating, err = strconv.Atoi(rating); err != nil {
+		return line, Record{}, &lineError{err: fmt.Errorf("invalid rating %q", rating)}
+	}
+	return line, record, nil
+}
@@ -0,0 +1,188 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+// importChunk is how many valid rows are written per INSERT and pipeline
+const importChunk = 500
+
+// maxUsernameLength matches the users.username column
+const maxUsernameLength = 255
+
+// LineError reports why one line of an import was not imported
+type LineError struct {
+	Line     int    `json:"line"`
+	Username string `json:"username,omitempty"`
+	Error    string `json:"error"`
+}
+
+// Summary counts the outcome of an import
+type Summary struct {
+	Imported int `json:"imported"`
+	Failed   int `json:"failed"`
+}
+
+// Importer creates users from CSV or NDJSON files. Imported users are
+// established players, so they go straight onto the public leaderboards.
+type Importer struct {
+	userRepo    *repository.UserRepository
+	rankService *ranking.RankingService
+}
+
+func NewImporter(userRepo *repository.UserRepository, rankService *ranking.RankingService) *Importer {
+	return &Importer{
+		userRepo:    userRepo,
+		rankService: rankService,
+	}
+}
+
+type pendingRow struct {
+	line int
+	user *models.User
+}
+
+// Import reads r to the end, writing valid rows to PostgreSQL and Redis in
+// chunks. onError is called for every line that is not imported; returning
+// an error from it stops the import.
+func (im *Importer) Import(ctx context.Context, r io.Reader, format Format, onError func(LineError) error) (Summary, error) {
+	var summary Summary
+	fail := func(e LineError) error {
+		summary.Failed++
+		return onError(e)
+	}
+
+	reader, err := newRecordReader(r, format)
+	if err != nil {
+		return summary, err
+	}
+
+	seen := make(map[string]int)
+	var chunk []pendingRow
+	for {
+		line, record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var lineErr *lineError
+		if errors.As(err, &lineErr) {
+			if err := fail(LineError{Line: line, Error: lineErr.Error()}); err != nil {
+				return summary, err
+			}
+			continue
+		}
+		if err != nil {
+			return summary, fmt.Errorf("failed to read line %d: %w", line, err)
+		}
+
+		user, err := validate(record)
+		if err == nil {
+			if first, ok := seen[user.Username]; ok {
+				err = fmt.Errorf("username already used on line %d", first)
+			}
+		}
+		if err != nil {
+			if err := fail(LineError{Line: line, Username: record.Username, Error: err.Error()}); err != nil {
+				return summary, err
+			}
+			continue
+		}
+		seen[user.Username] = line
+
+		chunk = append(chunk, pendingRow{line: line, user: user})
+		if len(chunk) == importChunk {
+			if err := im.writeChunk(ctx, chunk, &summary, fail); err != nil {
+				return summary, err
+			}
+			chunk = chunk[:0]
+		}
+	}
+
+	if err := im.writeChunk(ctx, chunk, &summary, fail); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// validate applies the username, rating and region rules to a record
+func validate(record Record) (*models.User, error) {
+	username := strings.TrimSpace(record.Username)
+	switch {
+	case username == "":
+		return nil, errors.New("username is required")
+	case len(username) > maxUsernameLength:
+		return nil, fmt.Errorf("username cannot exceed %d bytes", maxUsernameLength)
+	case !utf8.ValidString(username):
+		return nil, errors.New("username must be valid UTF-8")
+	case strings.IndexFunc(username, unicode.IsControl) >= 0:
+		return nil, errors.New("username cannot contain control characters")
+	}
+
+	if record.Rating < 100 || record.Rating > 5000 {
+		return nil, fmt.Errorf("rating must be between 100 and 5000, got %d", record.Rating)
+	}
+
+	region, ok := ranking.ParseRegion(record.Region)
+	if !ok {
+		return nil, fmt.Errorf("region %q must be a two-letter country code", record.Region)
+	}
+
+	return &models.User{
+		Username: username,
+		Rating:   record.Rating,
+		Region:   region,
+		Status:   models.StatusActive,
+	}, nil
+}
+
+func (im *Importer) writeChunk(ctx context.Context, chunk []pendingRow, summary *Summary, fail func(LineError) error) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+
+	users := make([]*models.User, len(chunk))
+	for i, row := range chunk {
+		users[i] = row.user
+	}
+	skipped, err := im.userRepo.CreateMany(ctx, users)
+	if err != nil {
+		return err
+	}
+	taken := make(map[*models.User]bool, len(skipped))
+	for _, user := range skipped {
+		taken[user] = true
+	}
+
+	regions := make(map[int64]string)
+	writes := make([]ranking.RatingWrite, 0, len(users))
+	for i, user := range users {
+		if taken[user] {
+			if err := fail(LineError{Line: chunk[i].line, Username: user.Username, Error: repository.ErrUsernameTaken.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		regions[user.ID] = user.Region
+		writes = append(writes, ranking.RatingWrite{UserID: user.ID, Rating: user.Rating, Version: user.Version})
+	}
+
+	// Regions first so the ratings land in the regional sets too
+	if err := im.rankService.SetNewUserRegions(ctx, regions); err != nil {
+		return err
+	}
+	if err := im.rankService.UpdateUserRatings(ctx, writes); err != nil {
+		return fmt.Errorf("failed to update leaderboard: %w", err)
+	}
+	summary.Imported += len(writes)
+	return nil
+}
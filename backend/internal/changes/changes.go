@@ -0,0 +1,177 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+// ErrTrimmed means events after the requested version have been trimmed from
+// the stream; the client has to reload the leaderboard and start again
+var ErrTrimmed = errors.New("changes since this version are no longer available")
+
+// Event is one change to the global leaderboard. OldRating is 0 when the user
+// joined the board and NewRating is 0 when they left it.
+type Event struct {
+	Seq       int64     `json:"seq"`
+	UserID    int64     `json:"user_id"`
+	OldRating int       `json:"old_rating"`
+	NewRating int       `json:"new_rating"`
+	At        time.Time `json:"at"`
+}
+
+// Feed reads the leaderboard change stream. A single background reader blocks
+// on the stream and wakes every waiting request when events arrive, so
+// long-polling clients don't each hold a Redis connection.
+type Feed struct {
+	redis *redis.Client
+
+	mu   sync.Mutex
+	wake chan struct{}
+}
+
+func NewFeed(redis *redis.Client) *Feed {
+	return &Feed{
+		redis: redis,
+		wake:  make(chan struct{}),
+	}
+}
+
+// Run follows the stream until ctx is cancelled, waking waiters on every
+// batch of new events
+func (f *Feed) Run(ctx context.Context) {
+	lastID := "$"
+	for ctx.Err() == nil {
+		streams, err := f.redis.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{ranking.ChangesKey, lastID},
+			Count:   1000,
+			Block:   5 * time.Second,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Warning: failed to read change stream: %v", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+			continue
+		}
+
+		for _, stream := range streams {
+			if n := len(stream.Messages); n > 0 {
+				lastID = stream.Messages[n-1].ID
+			}
+		}
+		f.mu.Lock()
+		close(f.wake)
+		f.wake = make(chan struct{})
+		f.mu.Unlock()
+	}
+}
+
+func (f *Feed) waiter() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.wake
+}
+
+// Current returns the board version, the point to follow the feed from for a
+// client that has just loaded the leaderboard
+func (f *Feed) Current(ctx context.Context) (int64, error) {
+	version, err := f.redis.Get(ctx, ranking.BoardVersionKey).Int64()
+	if err != nil && err != redis.Nil {
+		return 0, fmt.Errorf("failed to get leaderboard version: %w", err)
+	}
+	return version, nil
+}
+
+// Since returns up to limit events after version since, oldest first
+func (f *Feed) Since(ctx context.Context, since int64, limit int) ([]Event, error) {
+	pipe := f.redis.Pipeline()
+	trimmedCmd := pipe.Get(ctx, ranking.ChangesTrimmedKey)
+	rangeCmd := pipe.XRangeN(ctx, ranking.ChangesKey, strconv.FormatInt(since+1, 10), "+", int64(limit))
+	_, _ = pipe.Exec(ctx)
+	for _, cmd := range []redis.Cmder{trimmedCmd, rangeCmd} {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to read change stream: %w", err)
+		}
+	}
+
+	// Versions skipped by changes that aren't streamed leave gaps too, so
+	// only the recorded trim point says whether events after since are gone
+	if trimmed, err := trimmedCmd.Int64(); err == nil && trimmed > since {
+		return nil, ErrTrimmed
+	}
+
+	events := make([]Event, 0, len(rangeCmd.Val()))
+	for _, message := range rangeCmd.Val() {
+		event, err := parseEvent(message)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Wait is Since, but if there are no events yet it waits up to timeout for
+// some to arrive
+func (f *Feed) Wait(ctx context.Context, since int64, limit int, timeout time.Duration) ([]Event, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		// Take the wake channel before reading so events that land in between
+		// still wake us
+		wake := f.waiter()
+		events, err := f.Since(ctx, since, limit)
+		if err != nil || len(events) > 0 || timeout <= 0 {
+			return events, err
+		}
+
+		select {
+		case <-wake:
+		case <-timer.C:
+			return events, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func parseSeq(id string) (int64, error) {
+	seq, _, _ := strings.Cut(id, "-")
+	return strconv.ParseInt(seq, 10, 64)
+}
+
+func parseEvent(message redis.XMessage) (Event, error) {
+	seq, err := parseSeq(message.ID)
+	if err != nil {
+		return Event{}, fmt.Errorf("invalid change id %q: %w", message.ID, err)
+	}
+
+	field := func(name string) int64 {
+		value, _ := message.Values[name].(string)
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
+	}
+	return Event{
+		Seq:       seq,
+		UserID:    field("user_id"),
+		OldRating: int(field("old_rating")),
+		NewRating: int(field("new_rating")),
+		At:        time.UnixMilli(field("at")).UTC(),
+	}, nil
+}
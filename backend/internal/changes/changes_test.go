@@ -0,0 +1,88 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+func newTestFeed(t *testing.T) (*Feed, *ranking.RankingService, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewFeed(client), ranking.NewRankingService(client), server
+}
+
+func TestSince(t *testing.T) {
+	feed, rankService, server := newTestFeed(t)
+	ctx := context.Background()
+
+	// Renames bump the version without streaming an event, leaving gaps
+	if err := rankService.UpdateUserRating(ctx, 1, 1500, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := rankService.Touch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := rankService.Touch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := rankService.UpdateUserRating(ctx, 1, 1600, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := feed.Current(ctx)
+	if err != nil || current != 4 {
+		t.Fatalf("Current = %d, %v; want 4", current, err)
+	}
+
+	events, err := feed.Since(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("Since across a gap: %v", err)
+	}
+	if len(events) != 1 || events[0].Seq != 4 || events[0].OldRating != 1500 || events[0].NewRating != 1600 {
+		t.Errorf("events = %+v, want user 1 moving from 1500 to 1600 at version 4", events)
+	}
+
+	events, err = feed.Since(ctx, 0, 1)
+	if err != nil || len(events) != 1 || events[0].Seq != 1 || events[0].UserID != 1 || events[0].OldRating != 0 {
+		t.Errorf("Since(0, 1) = %+v, %v; want user 1 joining at version 1", events, err)
+	}
+
+	// Once the stream has dropped version 1, only readers behind it are cut off
+	server.Set(ranking.ChangesTrimmedKey, "1")
+	if _, err := feed.Since(ctx, 0, 10); !errors.Is(err, ErrTrimmed) {
+		t.Errorf("Since before the trim point = %v, want ErrTrimmed", err)
+	}
+	if events, err := feed.Since(ctx, 1, 10); err != nil || len(events) != 1 {
+		t.Errorf("Since at the trim point = %+v, %v; want one event", events, err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	feed, rankService, _ := newTestFeed(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feed.Run(ctx)
+
+	// Nothing arrives: the wait times out empty
+	events, err := feed.Wait(ctx, 0, 10, 20*time.Millisecond)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("Wait = %+v, %v; want no events", events, err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		rankService.UpdateUserRating(context.Background(), 7, 1800, 0)
+	}()
+	events, err = feed.Wait(ctx, 0, 10, 5*time.Second)
+	if err != nil || len(events) != 1 || events[0].UserID != 7 {
+		t.Errorf("Wait = %+v, %v; want user 7's change", events, err)
+	}
+}
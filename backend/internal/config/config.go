@@ -3,12 +3,14 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	Port         string
+	GRPCPort     string
 	DBHost       string
 	DBPort       string
 	DBUser       string
@@ -17,6 +19,32 @@ type Config struct {
 	RedisAddr    string
 	RedisPassword string
 	RedisDB      int
+	Tiers        string
+	TeamAggregate string
+	TeamTopK     int
+	SnapshotInterval time.Duration
+	StatsCacheTTL    time.Duration
+	RecentOpponentWindow time.Duration
+	ProvisionalGames     int
+	AdminToken           string
+	IdempotencyTTL       time.Duration
+
+	// Circuit breaker for Redis reads; once open, leaderboards are served
+	// from PostgreSQL
+	RedisBreakerFailures int
+	RedisBreakerCooldown time.Duration
+
+	// Anti-cheat limits on rating updates; 0 disables a check
+	AntiCheatMaxDelta   int
+	AntiCheatMaxUpdates int
+	AntiCheatWindow     time.Duration
+	AntiCheatOutlierZ   float64
+
+	// Webhook delivery: attempts before a delivery is dead-lettered, the
+	// per-request timeout and how often the queue is polled
+	WebhookMaxAttempts  int
+	WebhookTimeout      time.Duration
+	WebhookPollInterval time.Duration
 }
 
 func Load() (*Config, error) {
@@ -29,8 +57,114 @@ func Load() (*Config, error) {
 		}
 	}
 
+	teamTopK := 5
+	if k := os.Getenv("TEAM_TOP_K"); k != "" {
+		if parsed, err := strconv.Atoi(k); err == nil {
+			teamTopK = parsed
+		}
+	}
+
+	snapshotInterval := time.Hour
+	if interval := os.Getenv("SNAPSHOT_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil && parsed > 0 {
+			snapshotInterval = parsed
+		}
+	}
+
+	statsCacheTTL := 30 * time.Second
+	if ttl := os.Getenv("STATS_CACHE_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			statsCacheTTL = parsed
+		}
+	}
+
+	recentOpponentWindow := 24 * time.Hour
+	if window := os.Getenv("RECENT_OPPONENT_WINDOW"); window != "" {
+		if parsed, err := time.ParseDuration(window); err == nil {
+			recentOpponentWindow = parsed
+		}
+	}
+
+	provisionalGames := 5
+	if games := os.Getenv("PROVISIONAL_GAMES"); games != "" {
+		if parsed, err := strconv.Atoi(games); err == nil && parsed >= 0 {
+			provisionalGames = parsed
+		}
+	}
+
+	idempotencyTTL := 24 * time.Hour
+	if ttl := os.Getenv("IDEMPOTENCY_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil && parsed > 0 {
+			idempotencyTTL = parsed
+		}
+	}
+
+	redisBreakerFailures := 5
+	if failures := os.Getenv("REDIS_BREAKER_FAILURES"); failures != "" {
+		if parsed, err := strconv.Atoi(failures); err == nil && parsed > 0 {
+			redisBreakerFailures = parsed
+		}
+	}
+
+	redisBreakerCooldown := 10 * time.Second
+	if cooldown := os.Getenv("REDIS_BREAKER_COOLDOWN"); cooldown != "" {
+		if parsed, err := time.ParseDuration(cooldown); err == nil && parsed > 0 {
+			redisBreakerCooldown = parsed
+		}
+	}
+
+	antiCheatMaxDelta := 1000
+	if delta := os.Getenv("ANTICHEAT_MAX_DELTA"); delta != "" {
+		if parsed, err := strconv.Atoi(delta); err == nil && parsed >= 0 {
+			antiCheatMaxDelta = parsed
+		}
+	}
+
+	antiCheatMaxUpdates := 30
+	if updates := os.Getenv("ANTICHEAT_MAX_UPDATES"); updates != "" {
+		if parsed, err := strconv.Atoi(updates); err == nil && parsed >= 0 {
+			antiCheatMaxUpdates = parsed
+		}
+	}
+
+	antiCheatWindow := time.Hour
+	if window := os.Getenv("ANTICHEAT_WINDOW"); window != "" {
+		if parsed, err := time.ParseDuration(window); err == nil && parsed > 0 {
+			antiCheatWindow = parsed
+		}
+	}
+
+	antiCheatOutlierZ := 4.0
+	if z := os.Getenv("ANTICHEAT_OUTLIER_Z"); z != "" {
+		if parsed, err := strconv.ParseFloat(z, 64); err == nil && parsed >= 0 {
+			antiCheatOutlierZ = parsed
+		}
+	}
+
+	webhookMaxAttempts := 8
+	if attempts := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); attempts != "" {
+		if parsed, err := strconv.Atoi(attempts); err == nil && parsed > 0 {
+			webhookMaxAttempts = parsed
+		}
+	}
+
+	webhookTimeout := 10 * time.Second
+	if timeout := os.Getenv("WEBHOOK_TIMEOUT"); timeout != "" {
+		if parsed, err := time.ParseDuration(timeout); err == nil && parsed > 0 {
+			webhookTimeout = parsed
+		}
+	}
+
+	webhookPollInterval := 2 * time.Second
+	if interval := os.Getenv("WEBHOOK_POLL_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil && parsed > 0 {
+			webhookPollInterval = parsed
+		}
+	}
+
 	return &Config{
 		Port:         getEnv("PORT", "8080"),
+		GRPCPort:     getEnv("GRPC_PORT", "9090"),
 		DBHost:       getEnv("DB_HOST", "localhost"),
 		DBPort:       getEnv("DB_PORT", "5432"),
 		DBUser:       getEnv("DB_USER", "postgres"),
@@ -39,6 +173,24 @@ func Load() (*Config, error) {
 		RedisAddr:    getEnv("REDIS_ADDR", "localhost:6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:      redisDB,
+		Tiers:        getEnv("TIERS", ""),
+		TeamAggregate: getEnv("TEAM_AGGREGATE", "avg"),
+		TeamTopK:     teamTopK,
+		SnapshotInterval: snapshotInterval,
+		StatsCacheTTL:    statsCacheTTL,
+		RecentOpponentWindow: recentOpponentWindow,
+		ProvisionalGames:     provisionalGames,
+		AdminToken:           getEnv("ADMIN_TOKEN", ""),
+		IdempotencyTTL:       idempotencyTTL,
+		RedisBreakerFailures: redisBreakerFailures,
+		RedisBreakerCooldown: redisBreakerCooldown,
+		AntiCheatMaxDelta:    antiCheatMaxDelta,
+		AntiCheatMaxUpdates:  antiCheatMaxUpdates,
+		AntiCheatWindow:      antiCheatWindow,
+		AntiCheatOutlierZ:    antiCheatOutlierZ,
+		WebhookMaxAttempts:   webhookMaxAttempts,
+		WebhookTimeout:       webhookTimeout,
+		WebhookPollInterval:  webhookPollInterval,
 	}, nil
 }
 
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Channel is the Redis pub/sub channel every event is published on
+const Channel = "leaderboard:events"
+
+type Type string
+
+const (
+	TierPromoted Type = "tier.promoted"
+	TierDemoted  Type = "tier.demoted"
+
+	// Top-10 entries and exits, and users passed by someone climbing
+	RankTopEntered Type = "rank.top10_entered"
+	RankTopLeft    Type = "rank.top10_left"
+	RankOvertaken  Type = "rank.overtaken"
+
+	// Ping is only sent to test a webhook
+	Ping Type = "ping"
+)
+
+// Types lists every event type that can be subscribed to
+var Types = []Type{TierPromoted, TierDemoted, RankTopEntered, RankTopLeft, RankOvertaken, Ping}
+
+type Event struct {
+	Type      Type                   `json:"type"`
+	UserID    int64                  `json:"user_id"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Handler is called in-process for every published event
+type Handler func(ctx context.Context, event Event)
+
+// Bus fans events out to in-process handlers and to the Redis channel
+type Bus struct {
+	redis    *redis.Client
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus(redis *redis.Client) *Bus {
+	return &Bus{redis: redis}
+}
+
+// Subscribe registers an in-process handler for all events
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish delivers the event to local handlers and publishes it on Channel
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if err := b.redis.Publish(ctx, Channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"matkis-assignment/backend/internal/changes"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/rating"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/search"
+	"matkis-assignment/backend/internal/tier"
+	"matkis-assignment/backend/internal/usercache"
+	leaderboardv1 "matkis-assignment/backend/proto/leaderboard/v1"
+)
+
+// DegradedKey is the response header set when a call was served from
+// PostgreSQL because Redis is unavailable, like X-Degraded over HTTP
+const DegradedKey = "x-degraded"
+
+// NewServer creates the gRPC server. It shares its services with the REST
+// API, so both read and write the same leaderboards.
+func NewServer(userRepo *repository.UserRepository, rankService *ranking.RankingService, searchService *search.SearchService, tierService *tier.Service, ratingService *rating.Service, displayCache *usercache.Cache, changeFeed *changes.Feed) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoverUnary, markDegraded),
+		grpc.ChainStreamInterceptor(recoverStream),
+	)
+	leaderboardv1.RegisterLeaderboardServiceServer(server, &service{
+		userRepo:      userRepo,
+		rankService:   rankService,
+		searchService: searchService,
+		tierService:   tierService,
+		ratingService: ratingService,
+		displayCache:  displayCache,
+		changeFeed:    changeFeed,
+	})
+	// Lets tools such as grpcurl list and call the service without the proto
+	reflection.Register(server)
+	return server
+}
+
+// markDegraded flags the call's context so ranking reads can report falling
+// back to PostgreSQL, and sets DegradedKey on the response if any did
+func markDegraded(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = ranking.WithDegradedFlag(ctx)
+	resp, err := handler(ctx, req)
+	if ranking.Degraded(ctx) {
+		grpc.SetHeader(ctx, metadata.Pairs(DegradedKey, "true"))
+	}
+	return resp, err
+}
+
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoverStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(srv, stream)
+}
+
+type service struct {
+	leaderboardv1.UnimplementedLeaderboardServiceServer
+
+	userRepo      *repository.UserRepository
+	rankService   *ranking.RankingService
+	searchService *search.SearchService
+	tierService   *tier.Service
+	ratingService *rating.Service
+	displayCache  *usercache.Cache
+	changeFeed    *changes.Feed
+}
+
+func (s *service) GetLeaderboard(ctx context.Context, req *leaderboardv1.GetLeaderboardRequest) (*leaderboardv1.GetLeaderboardResponse, error) {
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = 50
+	}
+	if limit < 1 || limit > 100 {
+		return nil, status.Error(codes.InvalidArgument, "limit must be between 1 and 100")
+	}
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset cannot be negative")
+	}
+	region, ok := ranking.ParseRegion(req.Region)
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "region must be a two-letter country code")
+	}
+
+	minRating, maxRating := int(req.MinRating), int(req.MaxRating)
+	if minRating < 0 || maxRating < 0 || (maxRating > 0 && minRating > maxRating) {
+		return nil, status.Error(codes.InvalidArgument, "invalid rating range")
+	}
+
+	var entries []ranking.LeaderboardEntry
+	var err error
+	switch {
+	case minRating > 0 || maxRating > 0:
+		if maxRating == 0 {
+			maxRating = 5000
+		}
+		entries, err = s.rankService.GetLeaderboardByRating(ctx, region, minRating, maxRating, limit, int(req.Offset))
+	case region != "":
+		entries, err = s.rankService.GetRegionalLeaderboard(ctx, region, limit, int(req.Offset))
+	default:
+		entries, err = s.rankService.GetLeaderboard(ctx, limit, int(req.Offset))
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	tiers, err := s.tierService.Resolver(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	userIDs := make([]int64, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+	}
+	users, err := s.displayCache.Get(ctx, userIDs)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &leaderboardv1.GetLeaderboardResponse{
+		Entries: make([]*leaderboardv1.LeaderboardEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		user, ok := users[entry.UserID]
+		if !ok {
+			continue
+		}
+		resp.Entries = append(resp.Entries, &leaderboardv1.LeaderboardEntry{
+			Rank:     int32(entry.Rank),
+			UserId:   entry.UserID,
+			Username: user.Username,
+			Rating:   int32(entry.Rating),
+			Region:   user.Region,
+			Tier:     tiers.TierFor(entry.Rating),
+		})
+	}
+	return resp, nil
+}
+
+// GetRank returns a user's ranks. As over HTTP, shadow-banned users get the
+// rank they would have.
+func (s *service) GetRank(ctx context.Context, req *leaderboardv1.GetRankRequest) (*leaderboardv1.GetRankResponse, error) {
+	ids := []int64{req.UserId}
+	ranks, err := s.rankService.GetRanksForUsers(ctx, ids)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	rank, ok := ranks[req.UserId]
+	if !ok {
+		user, err := s.userRepo.GetByID(ctx, req.UserId)
+		if err != nil {
+			return nil, statusError(err)
+		}
+		var regionalRank int
+		if user.Status == models.StatusShadowBanned {
+			rank, ok, err = s.rankService.ShadowRank(ctx, req.UserId)
+			if err != nil {
+				return nil, statusError(err)
+			}
+			regionalRank, _, err = s.rankService.RegionalShadowRank(ctx, req.UserId)
+			if err != nil {
+				return nil, statusError(err)
+			}
+		}
+		if !ok {
+			return nil, status.Error(codes.NotFound, "user not found in leaderboard")
+		}
+		return &leaderboardv1.GetRankResponse{
+			UserId:       req.UserId,
+			Rank:         int32(rank),
+			RegionalRank: int32(regionalRank),
+			Rating:       int32(user.Rating),
+		}, nil
+	}
+
+	regionalRanks, err := s.rankService.GetRegionalRanksForUsers(ctx, ids)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	rating, _, err := s.rankService.GetRating(ctx, req.UserId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &leaderboardv1.GetRankResponse{
+		UserId:       req.UserId,
+		Rank:         int32(rank),
+		RegionalRank: int32(regionalRanks[req.UserId]),
+		Rating:       int32(rating),
+	}, nil
+}
+
+func (s *service) SearchUsers(ctx context.Context, req *leaderboardv1.SearchUsersRequest) (*leaderboardv1.SearchUsersResponse, error) {
+	if req.Query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = 100
+	}
+	if limit < 1 || limit > 100 {
+		return nil, status.Error(codes.InvalidArgument, "limit must be between 1 and 100")
+	}
+
+	results, err := s.searchService.SearchUsers(ctx, req.Query, limit)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &leaderboardv1.SearchUsersResponse{
+		Users: make([]*leaderboardv1.User, len(results)),
+	}
+	for i, result := range results {
+		resp.Users[i] = &leaderboardv1.User{
+			UserId:       result.ID,
+			Username:     result.Username,
+			Rating:       int32(result.Rating),
+			Region:       result.Region,
+			Tier:         result.Tier,
+			GlobalRank:   int32(result.GlobalRank),
+			RegionalRank: int32(result.RegionalRank),
+			Provisional:  result.Provisional,
+		}
+	}
+	return resp, nil
+}
+
+func (s *service) UpdateRating(ctx context.Context, req *leaderboardv1.UpdateRatingRequest) (*leaderboardv1.UpdateRatingResponse, error) {
+	if req.Rating < 100 || req.Rating > 5000 {
+		return nil, status.Error(codes.InvalidArgument, "rating must be between 100 and 5000")
+	}
+	if req.ExpectedVersion < 0 {
+		return nil, status.Error(codes.InvalidArgument, "expected_version cannot be negative")
+	}
+
+	result, err := s.ratingService.Update(ctx, req.UserId, int(req.Rating), req.ExpectedVersion)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	// Suspicious updates are held until an admin approves them
+	if result.Review != nil {
+		return &leaderboardv1.UpdateRatingResponse{
+			Status:   leaderboardv1.UpdateRatingResponse_STATUS_HELD_FOR_REVIEW,
+			ReviewId: result.Review.ID,
+			Reasons:  result.Review.Reasons,
+		}, nil
+	}
+	return &leaderboardv1.UpdateRatingResponse{
+		Status:  leaderboardv1.UpdateRatingResponse_STATUS_APPLIED,
+		Version: result.User.Version,
+	}, nil
+}
+
+// statusError maps service errors to gRPC status codes
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, rating.ErrBanned):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, repository.ErrVersionConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, changes.ErrTrimmed):
+		return status.Error(codes.OutOfRange, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,280 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"matkis-assignment/backend/internal/anticheat"
+	"matkis-assignment/backend/internal/changes"
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/rating"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/tier"
+	"matkis-assignment/backend/internal/usercache"
+	leaderboardv1 "matkis-assignment/backend/proto/leaderboard/v1"
+)
+
+// newTestService wires the service to miniredis and a mocked PostgreSQL
+func newTestService(t *testing.T) (*service, *redis.Client, sqlmock.Sqlmock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	userRepo := repository.NewUserRepository(db)
+	rankService := ranking.NewRankingService(client)
+	return &service{
+		userRepo:      userRepo,
+		rankService:   rankService,
+		tierService:   tier.NewService(tier.DefaultTable, rankService, events.NewBus(client)),
+		ratingService: rating.NewService(userRepo, repository.NewReviewRepository(db), rankService, anticheat.NewService(), 0),
+		displayCache:  usercache.NewCache(client, userRepo),
+		changeFeed:    changes.NewFeed(client),
+	}, client, mock
+}
+
+// rate puts users on the leaderboard and in the display cache, named user<ID>
+func rate(t *testing.T, s *service, client *redis.Client, ratings map[int64]int) {
+	t.Helper()
+	ctx := context.Background()
+	for userID, r := range ratings {
+		if err := s.rankService.UpdateUserRating(ctx, userID, r, 0); err != nil {
+			t.Fatal(err)
+		}
+		display, _ := json.Marshal(usercache.Display{Username: fmt.Sprintf("user%d", userID)})
+		if err := client.HSet(ctx, usercache.DisplayKey(userID), "data", display).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// userRows builds the rows a user query returns, in userColumns order
+func userRows(users ...*models.User) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "rating", "region", "games_played", "provisional", "status", "version", "created_at", "updated_at",
+	})
+	for _, user := range users {
+		rows.AddRow(user.ID, user.Username, user.Rating, user.Region, user.GamesPlayed, user.Provisional,
+			user.Status, user.Version, user.CreatedAt, user.UpdatedAt)
+	}
+	return rows
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: repository.ErrUserNotFound, want: codes.NotFound},
+		{err: fmt.Errorf("update: %w", rating.ErrBanned), want: codes.PermissionDenied},
+		{err: repository.ErrVersionConflict, want: codes.FailedPrecondition},
+		{err: changes.ErrTrimmed, want: codes.OutOfRange},
+		{err: context.Canceled, want: codes.Canceled},
+		{err: context.DeadlineExceeded, want: codes.DeadlineExceeded},
+		{err: errors.New("connection refused"), want: codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := status.Code(statusError(tt.err)); got != tt.want {
+			t.Errorf("statusError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGetLeaderboardValidation(t *testing.T) {
+	s, _, _ := newTestService(t)
+
+	tests := []struct {
+		name string
+		req  *leaderboardv1.GetLeaderboardRequest
+	}{
+		{name: "limit too large", req: &leaderboardv1.GetLeaderboardRequest{Limit: 101}},
+		{name: "negative limit", req: &leaderboardv1.GetLeaderboardRequest{Limit: -1}},
+		{name: "negative offset", req: &leaderboardv1.GetLeaderboardRequest{Offset: -1}},
+		{name: "bad region", req: &leaderboardv1.GetLeaderboardRequest{Region: "Germany"}},
+		{name: "negative rating", req: &leaderboardv1.GetLeaderboardRequest{MinRating: -1}},
+		{name: "min above max", req: &leaderboardv1.GetLeaderboardRequest{MinRating: 2000, MaxRating: 1000}},
+	}
+	for _, tt := range tests {
+		_, err := s.GetLeaderboard(context.Background(), tt.req)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: err = %v, want InvalidArgument", tt.name, err)
+		}
+	}
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	s, client, _ := newTestService(t)
+	rate(t, s, client, map[int64]int{1: 2500, 2: 2000, 3: 1800, 4: 1800, 5: 1200})
+
+	tests := []struct {
+		name      string
+		req       *leaderboardv1.GetLeaderboardRequest
+		wantIDs   []int64
+		wantRanks []int32
+	}{
+		{
+			name:      "first page",
+			req:       &leaderboardv1.GetLeaderboardRequest{Limit: 3},
+			wantIDs:   []int64{1, 2, 4},
+			wantRanks: []int32{1, 2, 3},
+		},
+		{
+			name:      "ties share a rank",
+			req:       &leaderboardv1.GetLeaderboardRequest{Limit: 2, Offset: 2},
+			wantIDs:   []int64{4, 3},
+			wantRanks: []int32{3, 3},
+		},
+		{
+			name:      "rating range",
+			req:       &leaderboardv1.GetLeaderboardRequest{MinRating: 1500},
+			wantIDs:   []int64{1, 2, 4, 3},
+			wantRanks: []int32{1, 2, 3, 3},
+		},
+	}
+	for _, tt := range tests {
+		resp, err := s.GetLeaderboard(context.Background(), tt.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(resp.Entries) != len(tt.wantIDs) {
+			t.Errorf("%s: entries = %v, want users %v", tt.name, resp.Entries, tt.wantIDs)
+			continue
+		}
+		for i, entry := range resp.Entries {
+			if entry.UserId != tt.wantIDs[i] || entry.Rank != tt.wantRanks[i] {
+				t.Errorf("%s: entries[%d] = user %d rank %d, want user %d rank %d",
+					tt.name, i, entry.UserId, entry.Rank, tt.wantIDs[i], tt.wantRanks[i])
+			}
+			if want := fmt.Sprintf("user%d", entry.UserId); entry.Username != want || entry.Tier == "" {
+				t.Errorf("%s: entries[%d] = %+v, want username %s and a tier", tt.name, i, entry, want)
+			}
+		}
+	}
+}
+
+func TestGetRank(t *testing.T) {
+	s, client, mock := newTestService(t)
+	rate(t, s, client, map[int64]int{1: 2400, 2: 2000})
+
+	resp, err := s.GetRank(context.Background(), &leaderboardv1.GetRankRequest{UserId: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Rank != 2 || resp.Rating != 2000 {
+		t.Errorf("resp = %+v, want rank 2 at 2000", resp)
+	}
+
+	// Users off the board are looked up so unknown IDs and unranked users differ
+	mock.ExpectQuery("FROM users WHERE id").WithArgs(3).WillReturnRows(userRows())
+	if _, err := s.GetRank(context.Background(), &leaderboardv1.GetRankRequest{UserId: 3}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown user: err = %v, want NotFound", err)
+	}
+	mock.ExpectQuery("FROM users WHERE id").WithArgs(4).
+		WillReturnRows(userRows(&models.User{ID: 4, Username: "user4", Rating: 1500, Status: models.StatusActive}))
+	if _, err := s.GetRank(context.Background(), &leaderboardv1.GetRankRequest{UserId: 4}); status.Code(err) != codes.NotFound {
+		t.Errorf("unranked user: err = %v, want NotFound", err)
+	}
+}
+
+func TestGetRankShadowBanned(t *testing.T) {
+	s, client, mock := newTestService(t)
+	rate(t, s, client, map[int64]int{1: 2400, 2: 2000, 3: 1800})
+	if err := s.rankService.Moderate(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("FROM users WHERE id").WithArgs(2).
+		WillReturnRows(userRows(&models.User{ID: 2, Username: "user2", Rating: 2000, Status: models.StatusShadowBanned}))
+	resp, err := s.GetRank(context.Background(), &leaderboardv1.GetRankRequest{UserId: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Rank != 2 || resp.Rating != 2000 {
+		t.Errorf("resp = %+v, want the rank the user would have, 2", resp)
+	}
+}
+
+func TestSearchUsersValidation(t *testing.T) {
+	s, _, _ := newTestService(t)
+
+	for _, req := range []*leaderboardv1.SearchUsersRequest{
+		{},
+		{Query: "user", Limit: 101},
+		{Query: "user", Limit: -1},
+	} {
+		if _, err := s.SearchUsers(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%+v: err = %v, want InvalidArgument", req, err)
+		}
+	}
+}
+
+func TestUpdateRating(t *testing.T) {
+	s, _, mock := newTestService(t)
+
+	for _, req := range []*leaderboardv1.UpdateRatingRequest{
+		{UserId: 1, Rating: 99},
+		{UserId: 1, Rating: 5001},
+		{UserId: 1, Rating: 1500, ExpectedVersion: -1},
+	} {
+		if _, err := s.UpdateRating(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%+v: err = %v, want InvalidArgument", req, err)
+		}
+	}
+
+	mock.ExpectQuery("FROM users WHERE id").WithArgs(1).
+		WillReturnRows(userRows(&models.User{ID: 1, Username: "user1", Rating: 1500, Status: models.StatusActive, Version: 3, GamesPlayed: 10}))
+	mock.ExpectQuery("UPDATE users SET rating").WithArgs(1600, 1, 0).
+		WillReturnRows(userRows(&models.User{ID: 1, Username: "user1", Rating: 1600, Status: models.StatusActive, Version: 4, GamesPlayed: 10}))
+	resp, err := s.UpdateRating(context.Background(), &leaderboardv1.UpdateRatingRequest{UserId: 1, Rating: 1600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != leaderboardv1.UpdateRatingResponse_STATUS_APPLIED || resp.Version != 4 {
+		t.Errorf("resp = %+v, want applied at version 4", resp)
+	}
+
+	mock.ExpectQuery("FROM users WHERE id").WithArgs(1).
+		WillReturnRows(userRows(&models.User{ID: 1, Username: "user1", Rating: 1600, Status: models.StatusActive, Version: 4, GamesPlayed: 10}))
+	_, err = s.UpdateRating(context.Background(), &leaderboardv1.UpdateRatingRequest{UserId: 1, Rating: 1700, ExpectedVersion: 3})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("stale version: err = %v, want FailedPrecondition", err)
+	}
+}
+
+func TestWatchRanksValidation(t *testing.T) {
+	s, _, _ := newTestService(t)
+
+	tooMany := make([]int64, maxWatched+1)
+	for i := range tooMany {
+		tooMany[i] = int64(i + 1)
+	}
+	for _, req := range []*leaderboardv1.WatchRanksRequest{
+		{UserIds: tooMany},
+		{Since: -1},
+	} {
+		if err := s.WatchRanks(req, nil); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("err = %v, want InvalidArgument", err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"matkis-assignment/backend/internal/changes"
+	leaderboardv1 "matkis-assignment/backend/proto/leaderboard/v1"
+)
+
+const (
+	// maxWatched caps the users a single WatchRanks call can follow
+	maxWatched = 100
+
+	// watchWait is how long each read of the change feed waits for events
+	watchWait = 30 * time.Second
+)
+
+// WatchRanks follows the leaderboard change feed, the same one behind
+// GET /api/changes, and streams updates until the client goes away
+func (s *service) WatchRanks(req *leaderboardv1.WatchRanksRequest, stream leaderboardv1.LeaderboardService_WatchRanksServer) error {
+	if len(req.UserIds) > maxWatched {
+		return status.Errorf(codes.InvalidArgument, "at most %d users can be watched", maxWatched)
+	}
+	if req.Since < 0 {
+		return status.Error(codes.InvalidArgument, "since cannot be negative")
+	}
+	if len(req.UserIds) == 0 {
+		return s.watchAll(req.Since, stream)
+	}
+	return s.watchUsers(req.UserIds, stream)
+}
+
+// watchAll streams every change with the mover's rank as of when it is sent
+func (s *service) watchAll(since int64, stream leaderboardv1.LeaderboardService_WatchRanksServer) error {
+	ctx := stream.Context()
+	if since == 0 {
+		current, err := s.changeFeed.Current(ctx)
+		if err != nil {
+			return statusError(err)
+		}
+		since = current
+	}
+
+	for {
+		events, err := s.changeFeed.Wait(ctx, since, 1000, watchWait)
+		if err != nil {
+			return statusError(err)
+		}
+
+		for _, event := range events {
+			rank := 0
+			if event.NewRating > 0 {
+				if rank, _, err = s.rankService.RankForRating(ctx, event.NewRating); err != nil {
+					return statusError(err)
+				}
+			}
+			err := stream.Send(&leaderboardv1.WatchRanksResponse{
+				Seq:            event.Seq,
+				UserId:         event.UserID,
+				Rank:           int32(rank),
+				Rating:         int32(event.NewRating),
+				PreviousRating: int32(event.OldRating),
+				At:             timestamppb.New(event.At),
+			})
+			if err != nil {
+				return err
+			}
+			since = event.Seq
+		}
+	}
+}
+
+// standing is a watched user's place on the global leaderboard; zero if they
+// are not on it
+type standing struct {
+	rank   int
+	rating int
+}
+
+// watchUsers sends the watched users' current standings, then the standings
+// that differ each time the leaderboard changes
+func (s *service) watchUsers(userIDs []int64, stream leaderboardv1.LeaderboardService_WatchRanksServer) error {
+	ctx := stream.Context()
+
+	seen := make(map[int64]bool, len(userIDs))
+	unique := make([]int64, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if !seen[userID] {
+			seen[userID] = true
+			unique = append(unique, userID)
+		}
+	}
+
+	// Take the version before the snapshot so no change slips between them
+	since, err := s.changeFeed.Current(ctx)
+	if err != nil {
+		return statusError(err)
+	}
+	previous := make(map[int64]standing, len(unique))
+	at := time.Now()
+	for first := true; ; first = false {
+		current, err := s.standings(ctx, unique)
+		if err != nil {
+			return statusError(err)
+		}
+		for _, userID := range unique {
+			before, now := previous[userID], current[userID]
+			if !first && now == before {
+				continue
+			}
+			err := stream.Send(&leaderboardv1.WatchRanksResponse{
+				Seq:            since,
+				UserId:         userID,
+				Rank:           int32(now.rank),
+				PreviousRank:   int32(before.rank),
+				Rating:         int32(now.rating),
+				PreviousRating: int32(before.rating),
+				At:             timestamppb.New(at),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		previous = current
+
+		// Wait for the next batch of changes; any of them can move a watched
+		// user, whether it is theirs or someone passing them
+		for {
+			events, err := s.changeFeed.Wait(ctx, since, 1000, watchWait)
+			if errors.Is(err, changes.ErrTrimmed) {
+				// Too far behind to replay; the next snapshot catches up
+				if since, err = s.changeFeed.Current(ctx); err != nil {
+					return statusError(err)
+				}
+				at = time.Now()
+				break
+			}
+			if err != nil {
+				return statusError(err)
+			}
+			if n := len(events); n > 0 {
+				since, at = events[n-1].Seq, events[n-1].At
+				break
+			}
+		}
+	}
+}
+
+func (s *service) standings(ctx context.Context, userIDs []int64) (map[int64]standing, error) {
+	ranks, err := s.rankService.GetRanksForUsers(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	ranked := make([]int64, 0, len(ranks))
+	for userID := range ranks {
+		ranked = append(ranked, userID)
+	}
+	entries, err := s.rankService.RankAmong(ctx, ranked)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[int64]standing, len(entries))
+	for _, entry := range entries {
+		current[entry.UserID] = standing{rank: ranks[entry.UserID], rating: entry.Rating}
+	}
+	return current, nil
+}
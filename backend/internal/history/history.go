@@ -0,0 +1,436 @@
+package history
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+// snapshotChunk is how many members are read from the sorted set per round trip
+const snapshotChunk = 10000
+
+// cachedSnapshots is how many decoded snapshots are kept in memory. Reads
+// of the past mostly land on the latest few snapshots.
+const cachedSnapshots = 4
+
+// frozenTTL bounds how long a frozen copy of the leaderboard outlives a
+// snapshot that crashed before deleting it
+const frozenTTL = 10 * time.Minute
+
+var ErrNoHistory = errors.New("no leaderboard history at that time")
+
+// Service records rating changes and periodic snapshots of the global
+// leaderboard so standings can be reconstructed for any past moment
+type Service struct {
+	redis       *redis.Client
+	historyRepo *repository.HistoryRepository
+
+	mu        sync.Mutex
+	snapshots map[int64]board
+	cached    []int64 // snapshot IDs in snapshots, oldest first
+}
+
+// NewService creates a history service and subscribes it to rating changes
+func NewService(redis *redis.Client, historyRepo *repository.HistoryRepository, rankService *ranking.RankingService) *Service {
+	s := &Service{
+		redis:       redis,
+		historyRepo: historyRepo,
+		snapshots:   make(map[int64]board),
+	}
+	rankService.AddListener(s.handleRatingChange)
+	return s
+}
+
+func (s *Service) handleRatingChange(ctx context.Context, change ranking.RatingChange) {
+	var oldRating *int
+	if change.OldRating != 0 {
+		oldRating = &change.OldRating
+	}
+	var newRating *int
+	if change.NewRating != 0 {
+		newRating = &change.NewRating
+	}
+	if err := s.historyRepo.LogChange(ctx, change.UserID, oldRating, newRating); err != nil {
+		log.Printf("Warning: failed to log rating change for user %d: %v", change.UserID, err)
+	}
+}
+
+// RunSnapshots takes a snapshot every interval until ctx is cancelled
+func (s *Service) RunSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.TakeSnapshot(ctx); err != nil {
+				log.Printf("Warning: failed to take leaderboard snapshot: %v", err)
+			}
+		}
+	}
+}
+
+// TakeSnapshot stores the current global leaderboard as sorted, delta-encoded
+// varint (user ID, rating) pairs
+func (s *Service) TakeSnapshot(ctx context.Context) error {
+	// Changes logged after this instant are replayed on top of the snapshot,
+	// so writes racing with the read below are never lost
+	takenAt, err := s.historyRepo.Now(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := s.freeze(ctx, "snapshot")
+	if err != nil {
+		return err
+	}
+	defer s.redis.Del(context.Background(), key)
+
+	ratings := make(map[int64]int)
+	for start := int64(0); ; start += snapshotChunk {
+		results, err := s.redis.ZRangeWithScores(ctx, key, start, start+snapshotChunk-1).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read leaderboard: %w", err)
+		}
+		for _, result := range results {
+			userID, err := strconv.ParseInt(fmt.Sprintf("%v", result.Member), 10, 64)
+			if err != nil {
+				continue
+			}
+			ratings[userID] = int(result.Score)
+		}
+		if len(results) < snapshotChunk {
+			break
+		}
+	}
+
+	return s.historyRepo.SaveSnapshot(ctx, takenAt, len(ratings), encodeSnapshot(ratings))
+}
+
+// freeze copies the global leaderboard to a temporary key in one atomic step,
+// so it can be read in chunks without writes moving members between them.
+// The caller deletes the copy when done.
+func (s *Service) freeze(ctx context.Context, purpose string) (string, error) {
+	key := fmt.Sprintf("history:%s:%d", purpose, time.Now().UnixNano())
+	pipe := s.redis.TxPipeline()
+	pipe.ZUnionStore(ctx, key, &redis.ZStore{Keys: []string{ranking.LeaderboardKey}})
+	pipe.Expire(ctx, key, frozenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to copy leaderboard: %w", err)
+	}
+	return key, nil
+}
+
+func encodeSnapshot(ratings map[int64]int) []byte {
+	ids := make([]int64, 0, len(ratings))
+	for id := range ratings {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	buf := make([]byte, 0, len(ids)*4)
+	var prev int64
+	for _, id := range ids {
+		buf = binary.AppendUvarint(buf, uint64(id-prev))
+		buf = binary.AppendUvarint(buf, uint64(ratings[id]))
+		prev = id
+	}
+	return buf
+}
+
+func decodeSnapshot(data []byte) (map[int64]int, error) {
+	ratings := make(map[int64]int)
+	var prev int64
+	for len(data) > 0 {
+		delta, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("corrupt snapshot")
+		}
+		data = data[n:]
+		rating, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("corrupt snapshot")
+		}
+		data = data[n:]
+
+		prev += int64(delta)
+		ratings[prev] = int(rating)
+	}
+	return ratings, nil
+}
+
+// board is a decoded snapshot, ordered like the leaderboard: by rating
+// descending, then user ID. Ranks are left unset.
+type board []ranking.LeaderboardEntry
+
+func newBoard(ratings map[int64]int) board {
+	b := make(board, 0, len(ratings))
+	for userID, rating := range ratings {
+		b = append(b, ranking.LeaderboardEntry{UserID: userID, Rating: rating})
+	}
+	sort.Slice(b, func(i, j int) bool { return before(b[i], b[j]) })
+	return b
+}
+
+// before reports whether a sorts ahead of b on the leaderboard
+func before(a, b ranking.LeaderboardEntry) bool {
+	if a.Rating != b.Rating {
+		return a.Rating > b.Rating
+	}
+	return a.UserID < b.UserID
+}
+
+// snapshotAt returns the newest snapshot taken at or before asOf, decoded
+// once and then served from memory since snapshots are never rewritten. ok
+// is false when there is none.
+func (s *Service) snapshotAt(ctx context.Context, asOf time.Time) (board, time.Time, bool, error) {
+	id, takenAt, ok, err := s.historyRepo.LatestSnapshotAt(ctx, asOf)
+	if err != nil || !ok {
+		return nil, time.Time{}, false, err
+	}
+
+	s.mu.Lock()
+	b, cached := s.snapshots[id]
+	s.mu.Unlock()
+	if cached {
+		return b, takenAt, true, nil
+	}
+
+	data, err := s.historyRepo.SnapshotData(ctx, id)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	ratings, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	b = newBoard(ratings)
+
+	s.mu.Lock()
+	if _, cached := s.snapshots[id]; !cached {
+		s.snapshots[id] = b
+		s.cached = append(s.cached, id)
+		if len(s.cached) > cachedSnapshots {
+			delete(s.snapshots, s.cached[0])
+			s.cached = s.cached[1:]
+		}
+	}
+	s.mu.Unlock()
+	return b, takenAt, true, nil
+}
+
+// changesAt replays the change log over (from, asOf] into each changed user's
+// rating at asOf, 0 for users who had left the leaderboard
+func (s *Service) changesAt(ctx context.Context, from, asOf time.Time) (map[int64]int, error) {
+	changed := make(map[int64]int)
+	err := s.historyRepo.ForEachChange(ctx, from, asOf, func(change models.RatingChange) {
+		if change.NewRating == nil {
+			changed[change.UserID] = 0
+			return
+		}
+		changed[change.UserID] = *change.NewRating
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// RatingsAt reconstructs every user's rating at asOf from the newest earlier
+// snapshot plus the change log. Deleted users are left out, even for moments
+// before they were deleted.
+func (s *Service) RatingsAt(ctx context.Context, asOf time.Time) (map[int64]int, error) {
+	snapshot, from, _, err := s.snapshotAt(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := s.changesAt(ctx, from, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots are never rewritten, so they still hold users deleted since.
+	// Their logged changes went with their row, so only the snapshot can
+	// bring them back.
+	ratings := make(map[int64]int, len(snapshot)+len(changed))
+	ids := make([]int64, 0, len(snapshot))
+	for _, entry := range snapshot {
+		if _, ok := changed[entry.UserID]; !ok {
+			ids = append(ids, entry.UserID)
+		}
+	}
+	existing, err := s.historyRepo.ExistingUsers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range snapshot {
+		if existing[entry.UserID] {
+			ratings[entry.UserID] = entry.Rating
+		}
+	}
+	for userID, rating := range changed {
+		if rating != 0 {
+			ratings[userID] = rating
+		}
+	}
+
+	if len(ratings) == 0 {
+		return nil, ErrNoHistory
+	}
+	return ratings, nil
+}
+
+// GetLeaderboardAt returns a page of the leaderboard as it stood at asOf. The
+// snapshot and the users changed since it are merged in leaderboard order
+// only as far as the page reaches.
+func (s *Service) GetLeaderboardAt(ctx context.Context, asOf time.Time, limit, offset int) ([]ranking.LeaderboardEntry, error) {
+	snapshot, from, _, err := s.snapshotAt(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := s.changesAt(ctx, from, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changed users leave their snapshot position and rejoin at their rating then
+	moved := make(board, 0, len(changed))
+	for userID, rating := range changed {
+		if rating != 0 {
+			moved = append(moved, ranking.LeaderboardEntry{UserID: userID, Rating: rating})
+		}
+	}
+	sort.Slice(moved, func(i, j int) bool { return before(moved[i], moved[j]) })
+
+	entries := make([]ranking.LeaderboardEntry, 0, limit)
+	seen, rank, prevRating := 0, 0, -1
+	i, j := 0, 0
+	for len(entries) < limit {
+		// Take the next candidates in order, then drop snapshot users deleted
+		// since; their changes went with their row, so moved users still exist
+		need := offset + limit - seen
+		batch := make(board, 0, min(need, len(snapshot)-i+len(moved)-j))
+		var ids []int64
+		for len(batch) < need {
+			if i < len(snapshot) {
+				if _, ok := changed[snapshot[i].UserID]; ok {
+					i++
+					continue
+				}
+			}
+			if i < len(snapshot) && (j == len(moved) || before(snapshot[i], moved[j])) {
+				batch = append(batch, snapshot[i])
+				ids = append(ids, snapshot[i].UserID)
+				i++
+			} else if j < len(moved) {
+				batch = append(batch, moved[j])
+				j++
+			} else {
+				break
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		existing, err := s.historyRepo.ExistingUsers(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range batch {
+			if _, ok := changed[entry.UserID]; !ok && !existing[entry.UserID] {
+				continue
+			}
+			// Tie-aware ranks over everything ahead of the page
+			if entry.Rating != prevRating {
+				rank, prevRating = seen+1, entry.Rating
+			}
+			seen++
+			if seen > offset {
+				entry.Rank = rank
+				entries = append(entries, entry)
+			}
+		}
+		if len(batch) < need {
+			break
+		}
+	}
+
+	if seen == 0 {
+		return nil, ErrNoHistory
+	}
+	return entries, nil
+}
+
+// GetRankAt returns a user's tie-aware rank and rating at asOf. ok is false
+// if the user was not on the leaderboard then. Only users rated above them
+// are counted, as the snapshot and change log are replayed.
+func (s *Service) GetRankAt(ctx context.Context, userID int64, asOf time.Time) (int, int, bool, error) {
+	snapshot, from, _, err := s.snapshotAt(ctx, asOf)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	changed, err := s.changesAt(ctx, from, asOf)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	rating, changedSince := changed[userID]
+	if !changedSince {
+		for _, entry := range snapshot {
+			if entry.UserID == userID {
+				rating = entry.Rating
+				break
+			}
+		}
+	}
+	if rating == 0 {
+		return 0, 0, false, nil
+	}
+
+	higherCount := 0
+	for _, other := range changed {
+		if other > rating {
+			higherCount++
+		}
+	}
+
+	// Snapshot users ahead of the rating lead the board; only those still at
+	// their snapshot rating and not deleted since count
+	ahead := sort.Search(len(snapshot), func(i int) bool { return snapshot[i].Rating <= rating })
+	ids := make([]int64, 0, ahead+1)
+	for _, entry := range snapshot[:ahead] {
+		if _, ok := changed[entry.UserID]; !ok {
+			ids = append(ids, entry.UserID)
+		}
+	}
+	if !changedSince {
+		ids = append(ids, userID)
+	}
+	existing, err := s.historyRepo.ExistingUsers(ctx, ids)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if !changedSince && !existing[userID] {
+		return 0, 0, false, nil
+	}
+	for _, id := range ids {
+		if id != userID && existing[id] {
+			higherCount++
+		}
+	}
+	return higherCount + 1, rating, true, nil
+}
@@ -0,0 +1,250 @@
+package history
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func newTestService(t *testing.T) (*Service, *redis.Client, *miniredis.Miniredis, sqlmock.Sqlmock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	s := NewService(client, repository.NewHistoryRepository(db), ranking.NewRankingService(client))
+	return s, client, server, mock
+}
+
+// seed writes ratings straight to the global sorted set
+func seed(t *testing.T, client *redis.Client, ratings map[int64]int) {
+	t.Helper()
+	for userID, rating := range ratings {
+		err := client.ZAdd(context.Background(), ranking.LeaderboardKey, &redis.Z{Score: float64(rating), Member: userID}).Err()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// expectSnapshot answers the lookup of the newest snapshot and, unless it is
+// already cached, the read of its data
+func expectSnapshot(mock sqlmock.Sqlmock, id int64, takenAt time.Time, ratings map[int64]int) {
+	mock.ExpectQuery("FROM leaderboard_snapshots").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "taken_at"}).AddRow(id, takenAt))
+	if ratings != nil {
+		mock.ExpectQuery("SELECT data FROM leaderboard_snapshots").WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow(encodeSnapshot(ratings)))
+	}
+}
+
+// expectExisting answers the check for which snapshot users still exist
+func expectExisting(mock sqlmock.Sqlmock, ids ...int64) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range ids {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users")).WillReturnRows(rows)
+}
+
+func TestSnapshotEncoding(t *testing.T) {
+	ratings := map[int64]int{3: 1500, 1: 2000, 1000000: 100, 42: 4999}
+	decoded, err := decodeSnapshot(encodeSnapshot(ratings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(ratings) {
+		t.Fatalf("decoded %v, want %v", decoded, ratings)
+	}
+	for userID, rating := range ratings {
+		if decoded[userID] != rating {
+			t.Errorf("rating of %d = %d, want %d", userID, decoded[userID], rating)
+		}
+	}
+
+	if _, err := decodeSnapshot([]byte{0x80}); err == nil {
+		t.Error("decoded a truncated snapshot")
+	}
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	s, client, server, mock := newTestService(t)
+	ratings := map[int64]int{1: 2000, 2: 1500, 3: 1500}
+	seed(t, client, ratings)
+
+	takenAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT NOW()")).
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(takenAt))
+	mock.ExpectExec("INSERT INTO leaderboard_snapshots").
+		WithArgs(takenAt, 3, encodeSnapshot(ratings)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := s.TakeSnapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != ranking.LeaderboardKey {
+		t.Errorf("keys after snapshot = %v, want only the leaderboard", keys)
+	}
+}
+
+func TestGetLeaderboardAt(t *testing.T) {
+	s, _, _, mock := newTestService(t)
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOf := takenAt.Add(time.Hour)
+
+	expectSnapshot(mock, 1, takenAt, map[int64]int{1: 2000, 2: 1500, 3: 1400})
+	// User 3 joins user 2's rating, user 1 leaves and user 4 arrives
+	mock.ExpectQuery("FROM rating_changes").WithArgs(takenAt, asOf).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_rating", "new_rating", "changed_at"}).
+			AddRow(1, 3, 1400, 1500, takenAt).
+			AddRow(2, 1, 2000, nil, takenAt).
+			AddRow(3, 4, nil, 1200, takenAt))
+	expectExisting(mock, 2)
+
+	entries, err := s.GetLeaderboardAt(context.Background(), asOf, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ranking.LeaderboardEntry{
+		{UserID: 3, Rating: 1500, Rank: 1},
+		{UserID: 4, Rating: 1200, Rank: 3},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entries[%d] = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestGetLeaderboardAtPagesCachedSnapshot(t *testing.T) {
+	s, _, _, mock := newTestService(t)
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOf := takenAt.Add(time.Hour)
+	noChanges := func() {
+		mock.ExpectQuery("FROM rating_changes").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_rating", "new_rating", "changed_at"}))
+	}
+
+	ratings := make(map[int64]int)
+	for id := int64(1); id <= 100; id++ {
+		ratings[id] = 5000 - int(id)*10
+	}
+	expectSnapshot(mock, 7, takenAt, ratings)
+	noChanges()
+	// Only the users up to the end of the page are checked
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+	entries, err := s.GetLeaderboardAt(context.Background(), asOf, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].UserID != 2 || entries[0].Rank != 2 || entries[1].UserID != 3 {
+		t.Errorf("entries = %+v, want users 2 and 3", entries)
+	}
+
+	// The second read reuses the decoded snapshot, and user 1 has since been
+	// deleted so the page moves up
+	expectSnapshot(mock, 7, takenAt, nil)
+	noChanges()
+	expectExisting(mock, 2, 3)
+	expectExisting(mock, 4)
+	entries, err = s.GetLeaderboardAt(context.Background(), asOf, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].UserID != 3 || entries[0].Rank != 2 || entries[1].UserID != 4 {
+		t.Errorf("entries = %+v, want users 3 and 4 ranked from 2", entries)
+	}
+}
+
+func TestRatingsAtSkipsDeletedUsers(t *testing.T) {
+	s, _, _, mock := newTestService(t)
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOf := takenAt.Add(time.Hour)
+
+	// User 2 was deleted after the snapshot, taking their logged changes along
+	expectSnapshot(mock, 1, takenAt, map[int64]int{1: 2000, 2: 1500})
+	mock.ExpectQuery("FROM rating_changes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_rating", "new_rating", "changed_at"}))
+	expectExisting(mock, 1)
+
+	ratings, err := s.RatingsAt(context.Background(), asOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ratings) != 1 || ratings[1] != 2000 {
+		t.Errorf("ratings = %v, want only user 1", ratings)
+	}
+}
+
+func TestRatingsAtWithoutHistory(t *testing.T) {
+	s, _, _, mock := newTestService(t)
+	asOf := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM leaderboard_snapshots").WillReturnRows(sqlmock.NewRows([]string{"id", "taken_at"}))
+	mock.ExpectQuery("FROM rating_changes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_rating", "new_rating", "changed_at"}))
+
+	if _, err := s.RatingsAt(context.Background(), asOf); err != ErrNoHistory {
+		t.Errorf("RatingsAt before any history = %v, want ErrNoHistory", err)
+	}
+}
+
+func TestGetRankAt(t *testing.T) {
+	s, _, _, mock := newTestService(t)
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOf := takenAt.Add(time.Hour)
+
+	// User 4 climbs past user 3, user 2 ties with user 5, and user 1 has been
+	// deleted since the snapshot
+	expectSnapshot(mock, 1, takenAt, map[int64]int{1: 2500, 2: 2000, 3: 1800, 4: 1500, 5: 1700})
+	mock.ExpectQuery("FROM rating_changes").WithArgs(takenAt, asOf).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_rating", "new_rating", "changed_at"}).
+			AddRow(1, 4, 1500, 1900, takenAt).
+			AddRow(2, 2, 2000, 1700, takenAt))
+	expectExisting(mock, 3, 5)
+
+	rank, rating, ok, err := s.GetRankAt(context.Background(), 5, asOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || rank != 3 || rating != 1700 {
+		t.Errorf("GetRankAt = %d, %d, %v; want rank 3 at 1700", rank, rating, ok)
+	}
+}
+
+func TestGetRankAtDeletedUser(t *testing.T) {
+	s, _, _, mock := newTestService(t)
+	takenAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expectSnapshot(mock, 1, takenAt, map[int64]int{1: 2500, 2: 2000})
+	mock.ExpectQuery("FROM rating_changes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_rating", "new_rating", "changed_at"}))
+	expectExisting(mock, 1)
+
+	if _, _, ok, err := s.GetRankAt(context.Background(), 2, takenAt); err != nil || ok {
+		t.Errorf("GetRankAt for a deleted user = %v, %v; want not found", ok, err)
+	}
+}
@@ -0,0 +1,101 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"matkis-assignment/backend/internal/models"
+)
+
+// RunDailyRankSnapshots records every user's rank shortly after each UTC
+// midnight until ctx is cancelled
+func (s *Service) RunDailyRankSnapshots(ctx context.Context) {
+	for {
+		now := time.Now().UTC()
+		next := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		// The snapshot describes standings at the end of the previous day
+		if err := s.TakeRankSnapshot(ctx, next.Add(-24*time.Hour)); err != nil {
+			log.Printf("Warning: failed to take rank snapshot: %v", err)
+		}
+	}
+}
+
+// TakeRankSnapshot walks the global sorted set and stores each user's
+// tie-aware rank for the given date, overwriting any earlier run that day
+func (s *Service) TakeRankSnapshot(ctx context.Context, date time.Time) error {
+	// Ranks carry over from one chunk to the next, so read a frozen copy: a
+	// write between chunks would otherwise skip or repeat members
+	key, err := s.freeze(ctx, "ranks")
+	if err != nil {
+		return err
+	}
+	defer s.redis.Del(context.Background(), key)
+
+	rank := 0
+	prevRating := -1
+
+	for start := int64(0); ; start += snapshotChunk {
+		results, err := s.redis.ZRevRangeWithScores(ctx, key, start, start+snapshotChunk-1).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read leaderboard: %w", err)
+		}
+
+		userIDs := make([]int64, 0, len(results))
+		ranks := make([]int, 0, len(results))
+		ratings := make([]int, 0, len(results))
+		for i, result := range results {
+			rating := int(result.Score)
+			if rating != prevRating {
+				rank = int(start) + i + 1
+				prevRating = rating
+			}
+
+			userID, err := strconv.ParseInt(fmt.Sprintf("%v", result.Member), 10, 64)
+			if err != nil {
+				continue
+			}
+			userIDs = append(userIDs, userID)
+			ranks = append(ranks, rank)
+			ratings = append(ratings, rating)
+		}
+
+		if len(userIDs) > 0 {
+			if err := s.historyRepo.SaveRankSnapshots(ctx, date, userIDs, ranks, ratings); err != nil {
+				return err
+			}
+		}
+		if len(results) < snapshotChunk {
+			return nil
+		}
+	}
+}
+
+// GetRankHistory returns a user's daily ranks for the last `days` days
+func (s *Service) GetRankHistory(ctx context.Context, userID int64, days int) ([]models.RankSnapshot, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	return s.historyRepo.GetRankHistory(ctx, userID, since)
+}
+
+// GetMovers returns the biggest climbers and fallers between the latest rank
+// snapshot and the one `days` before it
+func (s *Service) GetMovers(ctx context.Context, days, limit int) ([]models.RankMover, []models.RankMover, error) {
+	climbers, err := s.historyRepo.GetMovers(ctx, days, true, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	fallers, err := s.historyRepo.GetMovers(ctx, days, false, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return climbers, fallers, nil
+}
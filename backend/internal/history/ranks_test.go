@@ -0,0 +1,31 @@
+package history
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+func TestTakeRankSnapshot(t *testing.T) {
+	s, client, server, mock := newTestService(t)
+	seed(t, client, map[int64]int{1: 2000, 2: 1800, 3: 1800, 4: 1500})
+
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO rank_snapshots").
+		WithArgs("2024-03-01",
+			pq.Array([]int64{1, 3, 2, 4}),
+			pq.Array([]int{1, 2, 2, 4}),
+			pq.Array([]int{2000, 1800, 1800, 1500})).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	if err := s.TakeRankSnapshot(context.Background(), date); err != nil {
+		t.Fatal(err)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != ranking.LeaderboardKey {
+		t.Errorf("keys after rank snapshot = %v, want only the leaderboard", keys)
+	}
+}
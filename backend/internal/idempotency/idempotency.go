@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// Header is the request header carrying the client's idempotency key
+const Header = "Idempotency-Key"
+
+const maxKeyLength = 255
+
+func redisKey(key string) string {
+	return "idempotency:" + key
+}
+
+// record is what's stored under a key: the request fingerprint, and once the
+// first request has finished, its response
+type record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status,omitempty"`
+	ContentType string      `json:"content_type,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// replayedHeader picks the response headers a replay repeats, such as ETag
+// and X-Degraded. The content type is kept on its own and the length is
+// set again when the body is written.
+func replayedHeader(h http.Header) http.Header {
+	header := h.Clone()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	return header
+}
+
+// Store keeps responses to mutating requests in Redis so retries carrying the
+// same Idempotency-Key are answered without running the request again
+type Store struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+func NewStore(redis *redis.Client, ttl time.Duration) *Store {
+	return &Store{redis: redis, ttl: ttl}
+}
+
+// claim reserves key for a request. It returns the existing record if the key
+// has been used before.
+func (s *Store) claim(ctx context.Context, key, fingerprint string) (*record, bool, error) {
+	data, err := json.Marshal(record{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := s.redis.SetNX(ctx, redisKey(key), data, s.ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if ok {
+		return nil, true, nil
+	}
+
+	existing, err := s.redis.Get(ctx, redisKey(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			// Expired or released in between; let the caller retry
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	var rec record
+	if err := json.Unmarshal(existing, &rec); err != nil {
+		return nil, false, fmt.Errorf("failed to decode idempotency record: %w", err)
+	}
+	return &rec, false, nil
+}
+
+func (s *Store) save(ctx context.Context, key string, rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, redisKey(key), data, s.ttl).Err()
+}
+
+func (s *Store) release(ctx context.Context, key string) error {
+	return s.redis.Del(ctx, redisKey(key)).Err()
+}
+
+// responseRecorder copies everything written to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Middleware replays the stored response for mutating requests that reuse an
+// Idempotency-Key with the same method, path and body, and rejects reuse with
+// a different payload. Requests without the header are passed through.
+// Server errors, and 401 and 403 responses from middleware that aborted the
+// chain (authentication failures), are not stored so the client can retry
+// them. A 403 written by the handler itself, such as a banned user's update,
+// is final and replayed like any other client error.
+//
+// Routes in skip, given as registered (e.g. "/api/users/:id"), are passed
+// through too: streaming uploads the middleware would have to buffer, and
+// responses holding secrets that must not be kept in Redis.
+func (s *Store) Middleware(skip ...string) gin.HandlerFunc {
+	skipped := make(map[string]bool, len(skip))
+	for _, route := range skip {
+		skipped[route] = true
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions || skipped[c.FullPath()] {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key cannot exceed 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		ctx := c.Request.Context()
+		existing, claimed, err := s.claim(ctx, key, fingerprint)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !claimed {
+			switch {
+			case existing == nil:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request with this Idempotency-Key is being retried concurrently"})
+			case existing.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			case existing.Status == 0:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request with this Idempotency-Key is still in progress"})
+			default:
+				for name, values := range existing.Header {
+					c.Writer.Header()[name] = values
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.Status, existing.ContentType, existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		defer func() {
+			// Free the key if the handler panicked, so retries aren't stuck
+			if r := recover(); r != nil {
+				_ = s.release(context.Background(), key)
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		// Use a fresh context: the request may already be cancelled
+		status := recorder.Status()
+		authFailed := c.IsAborted() && (status == http.StatusUnauthorized || status == http.StatusForbidden)
+		if status >= http.StatusInternalServerError || authFailed {
+			_ = s.release(context.Background(), key)
+			return
+		}
+		_ = s.save(context.Background(), key, record{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Header:      replayedHeader(recorder.Header()),
+			Body:        recorder.body.Bytes(),
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package idempotency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestRouter mounts the middleware in front of a handler answering with
+// status and counting its calls. /skipped is excluded from the store, and
+// /guarded sits behind a middleware that aborts with status instead.
+func newTestRouter(t *testing.T, status int) (*gin.Engine, *int, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	calls := 0
+	handler := func(c *gin.Context) {
+		calls++
+		c.Header("ETag", fmt.Sprintf(`"%d"`, calls))
+		c.JSON(status, gin.H{"call": calls})
+	}
+	guard := func(c *gin.Context) {
+		calls++
+		c.AbortWithStatusJSON(status, gin.H{"error": "denied"})
+	}
+	router := gin.New()
+	router.Use(NewStore(client, time.Hour).Middleware("/skipped"))
+	router.POST("/things", handler)
+	router.POST("/skipped", handler)
+	router.POST("/guarded", guard, handler)
+	return router, &calls, server
+}
+
+func send(router *gin.Engine, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestReplay(t *testing.T) {
+	router, calls, _ := newTestRouter(t, http.StatusCreated)
+
+	first := send(router, "/things", "abc", `{"name":"a"}`)
+	retry := send(router, "/things", "abc", `{"name":"a"}`)
+	if *calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", *calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked as replayed")
+	}
+	if got := retry.Header().Get("ETag"); got != `"1"` {
+		t.Errorf("retry ETag = %s, want the first response's \"1\"", got)
+	}
+
+	if w := send(router, "/things", "abc", `{"name":"b"}`); w.Code != http.StatusConflict {
+		t.Errorf("reuse with another body: status = %d, want 409", w.Code)
+	}
+	if w := send(router, "/things", strings.Repeat("k", maxKeyLength+1), ""); w.Code != http.StatusBadRequest {
+		t.Errorf("overlong key: status = %d, want 400", w.Code)
+	}
+
+	send(router, "/things", "", `{"name":"a"}`)
+	send(router, "/things", "", `{"name":"a"}`)
+	if *calls != 3 {
+		t.Errorf("requests without a key ran %d times in total, want 3", *calls)
+	}
+}
+
+func TestFailuresAreNotStored(t *testing.T) {
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{path: "/guarded", status: http.StatusUnauthorized},
+		{path: "/guarded", status: http.StatusForbidden},
+		{path: "/things", status: http.StatusInternalServerError},
+		{path: "/things", status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		router, calls, server := newTestRouter(t, tt.status)
+		send(router, tt.path, "abc", "{}")
+		send(router, tt.path, "abc", "{}")
+		if *calls != 2 {
+			t.Errorf("%s %d: ran %d times, want 2", tt.path, tt.status, *calls)
+		}
+		if server.Exists(redisKey("abc")) {
+			t.Errorf("%s %d: key is still held", tt.path, tt.status)
+		}
+	}
+
+	// Client errors from the handler, 403 included, are final and replayed
+	for _, status := range []int{http.StatusBadRequest, http.StatusForbidden} {
+		router, calls, _ := newTestRouter(t, status)
+		send(router, "/things", "abc", "{}")
+		if w := send(router, "/things", "abc", "{}"); w.Code != status {
+			t.Errorf("%d: retry status = %d", status, w.Code)
+		}
+		if *calls != 1 {
+			t.Errorf("%d: handler ran %d times, want 1", status, *calls)
+		}
+	}
+}
+
+func TestSkippedRoutes(t *testing.T) {
+	router, calls, server := newTestRouter(t, http.StatusCreated)
+	send(router, "/skipped", "abc", "{}")
+	send(router, "/skipped", "abc", "{}")
+	if *calls != 2 {
+		t.Errorf("handler ran %d times, want 2", *calls)
+	}
+	if len(server.Keys()) != 0 {
+		t.Errorf("skipped route stored %v", server.Keys())
+	}
+}
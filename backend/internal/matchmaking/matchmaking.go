@@ -0,0 +1,130 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+// MaxWindow is the widest rating window searched; it covers the whole rating range
+const MaxWindow = 5000
+
+var ErrNotRanked = errors.New("user is not on the leaderboard")
+
+type Candidate struct {
+	UserID     int64
+	Rating     int
+	RatingDiff int
+}
+
+// Service uses the global leaderboard sorted set as a matchmaking index
+type Service struct {
+	redis        *redis.Client
+	rankService  *ranking.RankingService
+	matchRepo    *repository.MatchRepository
+	recentWindow time.Duration
+}
+
+func NewService(redis *redis.Client, rankService *ranking.RankingService, matchRepo *repository.MatchRepository, recentWindow time.Duration) *Service {
+	return &Service{
+		redis:        redis,
+		rankService:  rankService,
+		matchRepo:    matchRepo,
+		recentWindow: recentWindow,
+	}
+}
+
+// FindCandidates looks for count opponents rated within window of the user,
+// doubling the window until enough are found or MaxWindow is reached. The user
+// and anyone they played within the recent window are excluded. It returns the
+// candidates closest in rating first, and the window that was finally used.
+func (s *Service) FindCandidates(ctx context.Context, userID int64, window, count int) ([]Candidate, int, error) {
+	// Provisional users are matched against established players by their
+	// provisional rating so they can play their way onto the leaderboard
+	member := strconv.FormatInt(userID, 10)
+	rating, ok, err := s.rankService.GetRating(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ok {
+		return nil, 0, ErrNotRanked
+	}
+	if window < 1 {
+		window = 1
+	}
+
+	recent, err := s.matchRepo.RecentOpponents(ctx, userID, time.Now().Add(-s.recentWindow))
+	if err != nil {
+		return nil, 0, err
+	}
+	excluded := map[string]bool{member: true}
+	for _, id := range recent {
+		excluded[strconv.FormatInt(id, 10)] = true
+	}
+
+	// Each side only needs enough members to fill the result after exclusions
+	perSide := int64(count + len(excluded))
+
+	for {
+		pipe := s.redis.Pipeline()
+		below := pipe.ZRevRangeByScoreWithScores(ctx, ranking.LeaderboardKey, &redis.ZRangeBy{
+			Max:   strconv.Itoa(rating),
+			Min:   strconv.Itoa(rating - window),
+			Count: perSide,
+		})
+		above := pipe.ZRangeByScoreWithScores(ctx, ranking.LeaderboardKey, &redis.ZRangeBy{
+			Min:   strconv.Itoa(rating),
+			Max:   strconv.Itoa(rating + window),
+			Count: perSide,
+		})
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, 0, fmt.Errorf("failed to query rating window: %w", err)
+		}
+
+		seen := make(map[string]bool)
+		var candidates []Candidate
+		for _, result := range append(below.Val(), above.Val()...) {
+			id := fmt.Sprintf("%v", result.Member)
+			if excluded[id] || seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			candidateID, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				continue
+			}
+			diff := int(result.Score) - rating
+			candidates = append(candidates, Candidate{UserID: candidateID, Rating: int(result.Score), RatingDiff: diff})
+		}
+
+		if len(candidates) >= count || window >= MaxWindow {
+			sort.SliceStable(candidates, func(i, j int) bool {
+				return abs(candidates[i].RatingDiff) < abs(candidates[j].RatingDiff)
+			})
+			if len(candidates) > count {
+				candidates = candidates[:count]
+			}
+			return candidates, window, nil
+		}
+
+		window *= 2
+		if window > MaxWindow {
+			window = MaxWindow
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package matchmaking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func newTestService(t *testing.T, ratings map[int64]int) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	rankService := ranking.NewRankingService(client)
+	for userID, rating := range ratings {
+		if err := rankService.UpdateUserRating(context.Background(), userID, rating, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewService(client, rankService, repository.NewMatchRepository(db), time.Hour), mock
+}
+
+func expectRecent(mock sqlmock.Sqlmock, userID int64, opponents ...int64) {
+	rows := sqlmock.NewRows([]string{"opponent"})
+	for _, id := range opponents {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery("FROM matches").WithArgs(userID, sqlmock.AnyArg()).WillReturnRows(rows)
+}
+
+func TestFindCandidates(t *testing.T) {
+	s, mock := newTestService(t, map[int64]int{1: 1500, 2: 1520, 3: 1450, 4: 1600, 5: 1900, 6: 1490})
+
+	// User 6 played user 1 recently, so is skipped
+	expectRecent(mock, 1, 6)
+	candidates, window, err := s.FindCandidates(context.Background(), 1, 50, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 50 finds users 2 and 3; doubling to 100 adds user 4
+	if window != 100 {
+		t.Errorf("window = %d, want 100", window)
+	}
+	want := []Candidate{{UserID: 2, Rating: 1520, RatingDiff: 20}, {UserID: 3, Rating: 1450, RatingDiff: -50}, {UserID: 4, Rating: 1600, RatingDiff: 100}}
+	if len(candidates) != len(want) {
+		t.Fatalf("candidates = %+v, want %+v", candidates, want)
+	}
+	for i := range want {
+		if candidates[i] != want[i] {
+			t.Errorf("candidates[%d] = %+v, want %+v", i, candidates[i], want[i])
+		}
+	}
+}
+
+func TestFindCandidatesStopsAtMaxWindow(t *testing.T) {
+	s, mock := newTestService(t, map[int64]int{1: 1500, 2: 4900})
+
+	expectRecent(mock, 1)
+	candidates, window, err := s.FindCandidates(context.Background(), 1, 1000, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if window != MaxWindow || len(candidates) != 1 || candidates[0].UserID != 2 {
+		t.Errorf("FindCandidates = %+v, %d; want user 2 within %d", candidates, window, MaxWindow)
+	}
+}
+
+func TestFindCandidatesNotRanked(t *testing.T) {
+	s, _ := newTestService(t, map[int64]int{1: 1500})
+	if _, _, err := s.FindCandidates(context.Background(), 7, 100, 5); err != ErrNotRanked {
+		t.Errorf("FindCandidates for an unranked user = %v, want ErrNotRanked", err)
+	}
+}
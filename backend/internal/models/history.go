@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type RatingChange struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	OldRating *int      `json:"old_rating"`
+	NewRating *int      `json:"new_rating"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type RankSnapshot struct {
+	Date   string `json:"date"`
+	Rank   int    `json:"rank"`
+	Rating int    `json:"rating"`
+}
+
+type RankMover struct {
+	UserID       int64  `json:"user_id"`
+	Username     string `json:"username"`
+	PreviousRank int    `json:"previous_rank"`
+	Rank         int    `json:"rank"`
+	Change       int    `json:"change"`
+	Rating       int    `json:"rating"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type Match struct {
+	ID       int64     `json:"id" db:"id"`
+	PlayerA  int64     `json:"player_a" db:"player_a"`
+	PlayerB  int64     `json:"player_b" db:"player_b"`
+	WinnerID *int64    `json:"winner_id" db:"winner_id"`
+	PlayedAt time.Time `json:"played_at" db:"played_at"`
+}
+
+type HeadToHead struct {
+	Matches int `json:"matches"`
+	AWins   int `json:"a_wins"`
+	BWins   int `json:"b_wins"`
+	Draws   int `json:"draws"`
+}
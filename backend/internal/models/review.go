@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Review states for RatingReview.Status
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// RatingReview is a rating update held back by the anti-cheat checks
+type RatingReview struct {
+	ID        int64 `json:"id" db:"id"`
+	UserID    int64 `json:"user_id" db:"user_id"`
+	OldRating int   `json:"old_rating" db:"old_rating"`
+	NewRating int   `json:"new_rating" db:"new_rating"`
+	// UserVersion is the user's version when the update was held
+	UserVersion int64      `json:"user_version" db:"user_version"`
+	Reasons     []string   `json:"reasons" db:"reasons"`
+	Status      string     `json:"status" db:"status"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at" db:"resolved_at"`
+}
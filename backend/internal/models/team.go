@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type Team struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type TeamLeaderboardEntry struct {
+	Rank   int     `json:"rank"`
+	TeamID int64   `json:"team_id"`
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+}
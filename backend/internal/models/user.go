@@ -2,17 +2,50 @@ package models
 
 import "time"
 
+// Moderation states for User.Status
+const (
+	StatusActive       = "active"
+	StatusHidden       = "hidden"
+	StatusBanned       = "banned"
+	StatusShadowBanned = "shadow_banned"
+)
+
 type User struct {
-	ID        int64     `json:"id" db:"id"`
-	Username  string    `json:"username" db:"username"`
-	Rating    int       `json:"rating" db:"rating"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID          int64     `json:"id" db:"id"`
+	Username    string    `json:"username" db:"username"`
+	Rating      int       `json:"rating" db:"rating"`
+	Region      string    `json:"region" db:"region"`
+	GamesPlayed int       `json:"games_played" db:"games_played"`
+	Provisional bool      `json:"provisional" db:"provisional"`
+	Status      string    `json:"status" db:"status"`
+	Version     int64     `json:"version" db:"version"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Public returns the user as every non-admin response shows them: a shadow
+// ban looks like an active account, to the user and everyone else
+func (u User) Public() User {
+	if u.Status == StatusShadowBanned {
+		u.Status = StatusActive
+	}
+	return u
+}
+
+// PublicUsers applies Public to a list of users
+func PublicUsers(users []*User) []User {
+	public := make([]User, len(users))
+	for i, user := range users {
+		public[i] = user.Public()
+	}
+	return public
 }
 
 type UserWithRank struct {
 	User
-	GlobalRank int `json:"global_rank"`
+	GlobalRank   int    `json:"global_rank"`
+	RegionalRank int    `json:"regional_rank"`
+	Tier         string `json:"tier"`
 }
 
 type LeaderboardEntry struct {
@@ -20,4 +53,24 @@ type LeaderboardEntry struct {
 	Username string `json:"username"`
 	Rating   int    `json:"rating"`
 	UserID   int64  `json:"user_id"`
+	Region   string `json:"region"`
+	Tier     string `json:"tier"`
+}
+
+type UsernameChange struct {
+	OldUsername string    `json:"old_username" db:"old_username"`
+	NewUsername string    `json:"new_username" db:"new_username"`
+	ChangedAt   time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// UserExport bundles everything stored about a user for a data export
+type UserExport struct {
+	Profile         UserWithRank     `json:"profile"`
+	UsernameHistory []UsernameChange `json:"username_history"`
+	RatingHistory   []RatingChange   `json:"rating_history"`
+	RankHistory     []RankSnapshot   `json:"rank_history"`
+	FriendIDs       []int64          `json:"friend_ids"`
+	Matches         []*Match         `json:"matches"`
+	Reviews         []*RatingReview  `json:"rating_reviews"`
+	ExportedAt      time.Time        `json:"exported_at"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import "testing"
+
+func TestPublic(t *testing.T) {
+	for status, want := range map[string]string{
+		StatusActive:       StatusActive,
+		StatusHidden:       StatusHidden,
+		StatusBanned:       StatusBanned,
+		StatusShadowBanned: StatusActive,
+	} {
+		user := &User{ID: 1, Status: status}
+		if got := user.Public().Status; got != want {
+			t.Errorf("Public() status for %s = %s, want %s", status, got, want)
+		}
+		if public := PublicUsers([]*User{user}); public[0].Status != want || user.Status != status {
+			t.Errorf("PublicUsers for %s = %s, and the original became %s", status, public[0].Status, user.Status)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package rankevents
+
+import (
+	"context"
+	"log"
+
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+// TopN is the size of the top of the leaderboard that entry and exit events
+// are published for
+const TopN = 10
+
+// maxOvertaken caps the overtaken events published for a single rating change;
+// the users closest below the new rating are reported first
+const maxOvertaken = 20
+
+// Service publishes rank events for global leaderboard changes: users
+// entering or leaving the top 10, and users passed by someone climbing
+type Service struct {
+	rankService *ranking.RankingService
+	bus         *events.Bus
+}
+
+// NewService creates a rank event service and subscribes it to rating changes
+func NewService(rankService *ranking.RankingService, bus *events.Bus) *Service {
+	s := &Service{
+		rankService: rankService,
+		bus:         bus,
+	}
+	rankService.AddListener(s.handleRatingChange)
+	return s
+}
+
+func (s *Service) handleRatingChange(ctx context.Context, change ranking.RatingChange) {
+	if change.OldRating == change.NewRating {
+		return
+	}
+	if err := s.publishRankEvents(ctx, change); err != nil {
+		log.Printf("Warning: failed to publish rank events for user %d: %v", change.UserID, err)
+	}
+}
+
+// publishRankEvents runs after the change is written, so ranks on the board
+// are already the new ones. Old ranks are worked out from them: a user only
+// moves one place when someone else passes them.
+func (s *Service) publishRankEvents(ctx context.Context, change ranking.RatingChange) error {
+	newRank, oldRank := 0, 0
+	if change.NewRating > 0 {
+		rank, _, err := s.rankService.RankForRating(ctx, change.NewRating)
+		if err != nil {
+			return err
+		}
+		newRank = rank
+	}
+	if change.OldRating > 0 {
+		rank, _, err := s.rankService.RankForRating(ctx, change.OldRating)
+		if err != nil {
+			return err
+		}
+		// The user is now counted above their old rating if they climbed
+		if change.NewRating > change.OldRating {
+			rank--
+		}
+		oldRank = rank
+	}
+
+	inTop := func(rank int) bool { return rank > 0 && rank <= TopN }
+	switch {
+	case inTop(newRank) && !inTop(oldRank):
+		s.publish(ctx, events.RankTopEntered, change.UserID, map[string]interface{}{
+			"rank":          newRank,
+			"previous_rank": nullableRank(oldRank),
+			"rating":        change.NewRating,
+		})
+	case inTop(oldRank) && !inTop(newRank):
+		s.publish(ctx, events.RankTopLeft, change.UserID, map[string]interface{}{
+			"rank":          nullableRank(newRank),
+			"previous_rank": oldRank,
+			"rating":        change.NewRating,
+		})
+	}
+
+	if change.NewRating > change.OldRating {
+		// Everyone rated from the old rating up to just below the new one
+		// lost a place
+		low := change.OldRating
+		if low == 0 {
+			low = 1
+		}
+		if inTop(newRank) {
+			if err := s.publishShifted(ctx, events.RankTopLeft, TopN+1, low, change.NewRating-1); err != nil {
+				return err
+			}
+		}
+		if change.OldRating > 0 {
+			if err := s.publishOvertaken(ctx, change); err != nil {
+				return err
+			}
+		}
+	} else if inTop(oldRank) {
+		// Everyone rated strictly between the new and old rating gained a place
+		if err := s.publishShifted(ctx, events.RankTopEntered, TopN, change.NewRating+1, change.OldRating-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishShifted publishes eventType for the users now ranked rank whose
+// rating is within [low, high], i.e. those the change moved across the edge of
+// the top
+func (s *Service) publishShifted(ctx context.Context, eventType events.Type, rank, low, high int) error {
+	if low > high {
+		return nil
+	}
+	rating, ok, err := s.rankService.RatingAtPosition(ctx, rank-1)
+	if err != nil || !ok || rating < low || rating > high {
+		return err
+	}
+	// The user at that position may share a better rank with those above
+	actual, _, err := s.rankService.RankForRating(ctx, rating)
+	if err != nil || actual != rank {
+		return err
+	}
+
+	entries, err := s.rankService.GetLeaderboardByRating(ctx, "", rating, rating, maxOvertaken, 0)
+	if err != nil {
+		return err
+	}
+	previousRank := rank - 1
+	if eventType == events.RankTopEntered {
+		previousRank = rank + 1
+	}
+	for _, entry := range entries {
+		s.publish(ctx, eventType, entry.UserID, map[string]interface{}{
+			"rank":          entry.Rank,
+			"previous_rank": previousRank,
+			"rating":        entry.Rating,
+		})
+	}
+	return nil
+}
+
+func (s *Service) publishOvertaken(ctx context.Context, change ranking.RatingChange) error {
+	entries, err := s.rankService.GetLeaderboardByRating(ctx, "", change.OldRating, change.NewRating-1, maxOvertaken, 0)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.UserID == change.UserID {
+			continue
+		}
+		s.publish(ctx, events.RankOvertaken, entry.UserID, map[string]interface{}{
+			"by_user_id": change.UserID,
+			"by_rating":  change.NewRating,
+			"rank":       entry.Rank,
+			"rating":     entry.Rating,
+		})
+	}
+	return nil
+}
+
+func (s *Service) publish(ctx context.Context, eventType events.Type, userID int64, data map[string]interface{}) {
+	err := s.bus.Publish(ctx, events.Event{
+		Type:   eventType,
+		UserID: userID,
+		Data:   data,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to publish %s event for user %d: %v", eventType, userID, err)
+	}
+}
+
+// nullableRank reports a missing rank, for users joining or leaving the
+// board, as null
+func nullableRank(rank int) interface{} {
+	if rank == 0 {
+		return nil
+	}
+	return rank
+}
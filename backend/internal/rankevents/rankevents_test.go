@@ -0,0 +1,114 @@
+package rankevents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+// newTestService puts users 1 to 12 on the board rated 1010 to 1120, so
+// users 3 to 12 make up the top 10, and records every event published after
+func newTestService(t *testing.T) (*ranking.RankingService, *[]events.Event) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	rankService := ranking.NewRankingService(client)
+	for i := int64(1); i <= 12; i++ {
+		if err := rankService.UpdateUserRating(context.Background(), i, 1000+int(i)*10, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bus := events.NewBus(client)
+	NewService(rankService, bus)
+	got := &[]events.Event{}
+	bus.Subscribe(func(ctx context.Context, event events.Event) {
+		*got = append(*got, event)
+	})
+	return rankService, got
+}
+
+// byType groups events by type, keyed by user
+func byType(got []events.Event) map[events.Type]map[int64]events.Event {
+	grouped := make(map[events.Type]map[int64]events.Event)
+	for _, event := range got {
+		if grouped[event.Type] == nil {
+			grouped[event.Type] = make(map[int64]events.Event)
+		}
+		grouped[event.Type][event.UserID] = event
+	}
+	return grouped
+}
+
+func TestClimbIntoTop(t *testing.T) {
+	rankService, got := newTestService(t)
+
+	if err := rankService.UpdateUserRating(context.Background(), 1, 2000, 0); err != nil {
+		t.Fatal(err)
+	}
+	grouped := byType(*got)
+
+	entered := grouped[events.RankTopEntered]
+	if len(entered) != 1 || entered[1].Data["rank"] != 1 || entered[1].Data["previous_rank"] != 12 {
+		t.Errorf("top entered = %+v, want user 1 from 12th to 1st", entered)
+	}
+	left := grouped[events.RankTopLeft]
+	if len(left) != 1 || left[3].Data["rank"] != 11 || left[3].Data["previous_rank"] != 10 {
+		t.Errorf("top left = %+v, want user 3 pushed from 10th to 11th", left)
+	}
+	// Everyone from user 2 up was passed
+	overtaken := grouped[events.RankOvertaken]
+	if len(overtaken) != 11 {
+		t.Errorf("got %d overtaken events, want 11", len(overtaken))
+	}
+	if event := overtaken[12]; event.Data["by_user_id"] != int64(1) || event.Data["rank"] != 2 {
+		t.Errorf("overtaken event for user 12 = %+v, want passed by user 1, now 2nd", event)
+	}
+	if _, ok := overtaken[1]; ok {
+		t.Error("user was reported as overtaking themselves")
+	}
+}
+
+func TestDropOutOfTop(t *testing.T) {
+	rankService, got := newTestService(t)
+
+	// User 3, 10th on 1030, falls below user 2 on 1020, who takes their place
+	if err := rankService.UpdateUserRating(context.Background(), 3, 1015, 0); err != nil {
+		t.Fatal(err)
+	}
+	grouped := byType(*got)
+
+	left := grouped[events.RankTopLeft]
+	if len(left) != 1 || left[3].Data["rank"] != 11 || left[3].Data["previous_rank"] != 10 {
+		t.Errorf("top left = %+v, want user 3 from 10th to 11th", left)
+	}
+	entered := grouped[events.RankTopEntered]
+	if len(entered) != 1 || entered[2].Data["rank"] != 10 || entered[2].Data["previous_rank"] != 11 {
+		t.Errorf("top entered = %+v, want user 2 from 11th to 10th", entered)
+	}
+	if overtaken := grouped[events.RankOvertaken]; len(overtaken) != 0 {
+		t.Errorf("overtaken = %+v, want none for a drop", overtaken)
+	}
+}
+
+func TestNoEventsOutsideTop(t *testing.T) {
+	rankService, got := newTestService(t)
+
+	// User 1 passes user 2 but stays outside the top
+	if err := rankService.UpdateUserRating(context.Background(), 1, 1025, 0); err != nil {
+		t.Fatal(err)
+	}
+	grouped := byType(*got)
+	if len(grouped[events.RankTopEntered])+len(grouped[events.RankTopLeft]) != 0 {
+		t.Errorf("events = %+v, want no top changes", *got)
+	}
+	if overtaken := grouped[events.RankOvertaken]; len(overtaken) != 1 || overtaken[2].Data["rank"] != 12 {
+		t.Errorf("overtaken = %+v, want user 2, now 12th", overtaken)
+	}
+}
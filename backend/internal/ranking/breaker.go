@@ -0,0 +1,68 @@
+package ranking
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker is a circuit breaker for Redis. After threshold consecutive
+// failures it opens, and reads go straight to the fallback; once every
+// cooldown a single request is let through to probe whether Redis is back.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow reports whether a request should try Redis
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Since(b.openedAt) >= b.cooldown {
+		// Half-open: this caller probes, the rest wait for the next cooldown
+		b.openedAt = time.Now()
+		return true
+	}
+	return false
+}
+
+// Success closes the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+// Failure counts a failed request, opening the breaker at the threshold
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// Open reports whether Redis is currently considered unavailable
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
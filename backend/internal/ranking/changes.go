@@ -0,0 +1,66 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ChangesKey is a stream of every change to the global leaderboard. Entry IDs
+// are <board version>-0, so readers can resume from a version; versions
+// bumped by other changes leave gaps.
+const ChangesKey = "leaderboard:changes"
+
+// ChangesTrimmedKey holds the board version of the newest change dropped from
+// the stream, so readers can tell a gap left by trimming from one left by
+// changes that aren't streamed
+const ChangesTrimmedKey = "leaderboard:changes:trimmed"
+
+// MaxChanges is how many changes the stream keeps
+const MaxChanges = 100000
+
+// appendChangeLua defines append_change for the scripts that change the
+// global leaderboard. Once the stream is full the oldest change is trimmed
+// and its version recorded. XADD runs under pcall: if the stream has somehow
+// got ahead of the board version the event is lost, but the write still goes
+// through and the loss is recorded like a trim.
+const appendChangeLua = `
+local function append_change(stream, trimmed, seq, user, old, new, now, maxlen)
+	local added = redis.pcall('XADD', stream, seq .. '-0',
+		'user_id', user, 'old_rating', old, 'new_rating', new, 'at', now)
+	if type(added) ~= 'string' then
+		if tonumber(redis.call('GET', trimmed) or '0') < tonumber(seq) then
+			redis.call('SET', trimmed, seq)
+		end
+		return
+	end
+	local excess = redis.call('XLEN', stream) - tonumber(maxlen)
+	if excess > 0 then
+		local dropped = redis.call('XRANGE', stream, '-', '+', 'COUNT', excess)
+		redis.call('XTRIM', stream, 'MAXLEN', maxlen)
+		redis.call('SET', trimmed, string.match(dropped[#dropped][1], '^%d+'))
+	end
+end
+`
+
+// changeScript bumps the board version and appends one change under it
+//
+// KEYS: board version, board modified time, change stream, trimmed marker
+// ARGV: user ID, old rating, new rating, current Unix millis, stream length
+var changeScript = redis.NewScript(appendChangeLua + `
+local seq = redis.call('INCR', KEYS[1])
+redis.call('SET', KEYS[2], ARGV[4])
+append_change(KEYS[3], KEYS[4], seq, ARGV[1], ARGV[2], ARGV[3], ARGV[4], ARGV[5])
+return seq
+`)
+
+// recordChange bumps the board version and appends change to the stream as
+// part of a transaction, in place of bump
+func recordChange(ctx context.Context, tx redis.Pipeliner, change RatingChange) {
+	changeScript.Eval(ctx, tx,
+		[]string{BoardVersionKey, BoardModifiedKey, ChangesKey, ChangesTrimmedKey},
+		fmt.Sprintf("%d", change.UserID), change.OldRating, change.NewRating, time.Now().UnixMilli(), MaxChanges,
+	)
+}
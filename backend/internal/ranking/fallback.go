@@ -0,0 +1,84 @@
+package ranking
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+)
+
+// Fallback computes leaderboard reads from PostgreSQL for when Redis is
+// unavailable. It ranks the same users the global and regional sets hold:
+// established users whose status is active.
+type Fallback interface {
+	// LeaderboardPage returns users rated minRating..maxRating in descending
+	// order with their tie-aware ranks; an empty region means global
+	LeaderboardPage(ctx context.Context, region string, minRating, maxRating, limit, offset int) ([]LeaderboardEntry, error)
+	// RanksForUsers returns global ranks, or ranks within each user's own
+	// region if regional is set. Unranked users are omitted.
+	RanksForUsers(ctx context.Context, userIDs []int64, regional bool) (map[int64]int, error)
+	// CountRatings counts ranked users rated above and below rating, and in total
+	CountRatings(ctx context.Context, rating int) (higher, lower, total int, err error)
+	// CountInRanges counts ranked users per inclusive [min, max] rating range
+	CountInRanges(ctx context.Context, ranges [][2]int) ([]int, error)
+	// RatingAtPosition returns the rating at a zero-based position
+	RatingAtPosition(ctx context.Context, position int) (int, bool, error)
+	// Ratings returns the ratings of the given users who are ranked
+	Ratings(ctx context.Context, userIDs []int64) (map[int64]int, error)
+	// ShadowRank returns the global rank a moderated user's rating would
+	// have, or the rank within their region if regional is set; ok is false
+	// if the user is not moderated
+	ShadowRank(ctx context.Context, userID int64, regional bool) (rank int, ok bool, err error)
+}
+
+// SetFallback serves reads from fallback whenever breaker is open or a Redis
+// read fails
+func (s *RankingService) SetFallback(fallback Fallback, breaker *Breaker) {
+	s.fallback = fallback
+	s.breaker = breaker
+}
+
+// Healthy reports whether reads are currently going to Redis
+func (s *RankingService) Healthy() bool {
+	return s.breaker == nil || !s.breaker.Open()
+}
+
+type degradedKey struct{}
+
+// WithDegradedFlag returns a context in which fallback reads are recorded,
+// for Degraded to report afterwards
+func WithDegradedFlag(ctx context.Context) context.Context {
+	return context.WithValue(ctx, degradedKey{}, new(atomic.Bool))
+}
+
+// Degraded reports whether any read made with ctx was served by the fallback
+func Degraded(ctx context.Context) bool {
+	flag, ok := ctx.Value(degradedKey{}).(*atomic.Bool)
+	return ok && flag.Load()
+}
+
+// read runs a Redis read through the breaker, running fallback instead while
+// Redis is unavailable
+func (s *RankingService) read(ctx context.Context, primary, fallback func() error) error {
+	if s.fallback == nil {
+		return primary()
+	}
+
+	if s.breaker.Allow() {
+		err := primary()
+		if err == nil {
+			s.breaker.Success()
+			return nil
+		}
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		s.breaker.Failure()
+		log.Printf("Redis read failed, serving from PostgreSQL: %v", err)
+	}
+
+	if flag, ok := ctx.Value(degradedKey{}).(*atomic.Bool); ok {
+		flag.Store(true)
+	}
+	return fallback()
+}
@@ -0,0 +1,162 @@
+package ranking
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// stubFallback serves fixed answers and counts how often it was asked
+type stubFallback struct {
+	ratings map[int64]int
+	calls   int
+}
+
+func (f *stubFallback) LeaderboardPage(ctx context.Context, region string, minRating, maxRating, limit, offset int) ([]LeaderboardEntry, error) {
+	f.calls++
+	return []LeaderboardEntry{{UserID: 1, Rating: 2000, Rank: 1}}, nil
+}
+
+func (f *stubFallback) RanksForUsers(ctx context.Context, userIDs []int64, regional bool) (map[int64]int, error) {
+	f.calls++
+	ranks := make(map[int64]int)
+	for _, userID := range userIDs {
+		if rating, ok := f.ratings[userID]; ok {
+			higher := 0
+			for _, other := range f.ratings {
+				if other > rating {
+					higher++
+				}
+			}
+			ranks[userID] = higher + 1
+		}
+	}
+	return ranks, nil
+}
+
+func (f *stubFallback) CountRatings(ctx context.Context, rating int) (int, int, int, error) {
+	f.calls++
+	return 0, 0, len(f.ratings), nil
+}
+
+func (f *stubFallback) CountInRanges(ctx context.Context, ranges [][2]int) ([]int, error) {
+	f.calls++
+	counts := make([]int, len(ranges))
+	for i, r := range ranges {
+		for _, rating := range f.ratings {
+			if rating >= r[0] && rating <= r[1] {
+				counts[i]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+func (f *stubFallback) RatingAtPosition(ctx context.Context, position int) (int, bool, error) {
+	f.calls++
+	return 0, false, nil
+}
+
+func (f *stubFallback) Ratings(ctx context.Context, userIDs []int64) (map[int64]int, error) {
+	f.calls++
+	ratings := make(map[int64]int)
+	for _, userID := range userIDs {
+		if rating, ok := f.ratings[userID]; ok {
+			ratings[userID] = rating
+		}
+	}
+	return ratings, nil
+}
+
+func (f *stubFallback) ShadowRank(ctx context.Context, userID int64, regional bool) (int, bool, error) {
+	f.calls++
+	return 3, userID == 9, nil
+}
+
+func TestBreaker(t *testing.T) {
+	b := NewBreaker(2, 20*time.Millisecond)
+
+	b.Failure()
+	if b.Open() || !b.Allow() {
+		t.Fatal("breaker opened before the threshold")
+	}
+	b.Failure()
+	if !b.Open() || b.Allow() {
+		t.Fatal("breaker did not open at the threshold")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("breaker did not let a probe through after the cooldown")
+	}
+	if b.Allow() {
+		t.Error("breaker let a second probe through in the same cooldown")
+	}
+	b.Success()
+	if b.Open() || !b.Allow() {
+		t.Error("breaker did not close after a successful probe")
+	}
+}
+
+func TestReadsFallBackWhileRedisIsDown(t *testing.T) {
+	s, server := newTestService(t)
+	fallback := &stubFallback{ratings: map[int64]int{1: 2000, 2: 1800, 3: 1800, 4: 1500}}
+	breaker := NewBreaker(1, time.Hour)
+	s.SetFallback(fallback, breaker)
+	setRatings(t, s, fallback.ratings)
+
+	// Served by Redis while it is up
+	if rank, err := s.GetRank(context.Background(), 4); err != nil || rank != 4 {
+		t.Fatalf("GetRank = %d, %v; want 4", rank, err)
+	}
+	if fallback.calls != 0 || !s.Healthy() {
+		t.Fatalf("fallback was used %d times with Redis up", fallback.calls)
+	}
+
+	server.Close()
+	ctx := WithDegradedFlag(context.Background())
+
+	if rank, err := s.GetRank(ctx, 2); err != nil || rank != 2 {
+		t.Errorf("GetRank = %d, %v; want 2", rank, err)
+	}
+	if s.Healthy() {
+		t.Error("service still reports healthy after Redis failed")
+	}
+	if !Degraded(ctx) {
+		t.Error("context was not marked degraded")
+	}
+
+	if counts, err := s.CountInRanges(ctx, [][2]int{{1500, 1800}, {1900, 2500}}); err != nil || counts[0] != 3 || counts[1] != 1 {
+		t.Errorf("CountInRanges = %v, %v; want [3 1]", counts, err)
+	}
+	if count, err := s.CountBetween(ctx, 2000, 1500); err != nil || count != 2 {
+		t.Errorf("CountBetween = %d, %v; want 2", count, err)
+	}
+
+	entries, err := s.RankAmong(ctx, []int64{4, 2, 3, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LeaderboardEntry{{UserID: 2, Rating: 1800, Rank: 1}, {UserID: 3, Rating: 1800, Rank: 1}, {UserID: 4, Rating: 1500, Rank: 3}}
+	if len(entries) != len(want) {
+		t.Fatalf("RankAmong = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("RankAmong[%d] = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	if rank, ok, err := s.ShadowRank(ctx, 9); err != nil || !ok || rank != 3 {
+		t.Errorf("ShadowRank = %d, %v, %v; want 3, true", rank, ok, err)
+	}
+	if _, err := s.GetLeaderboard(ctx, 10, 0); err != nil {
+		t.Errorf("GetLeaderboard: %v", err)
+	}
+
+	// The open breaker skips Redis entirely, so nothing was tried after the
+	// first failure
+	if fallback.calls != 6 {
+		t.Errorf("fallback served %d reads, want 6", fallback.calls)
+	}
+}
@@ -0,0 +1,177 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ModeratedKey holds the ratings of hidden, banned and shadow-banned users.
+// They are kept out of the global and regional sets so they don't count
+// towards anyone else's rank.
+const ModeratedKey = "leaderboard:moderated"
+
+// Moderate moves a user off the public leaderboards into the moderated set.
+// It is a no-op for users who are already moderated or have no rating.
+func (s *RankingService) Moderate(ctx context.Context, userID int64) error {
+	member := fmt.Sprintf("%d", userID)
+
+	pipe := s.redis.Pipeline()
+	globalCmd := pipe.ZScore(ctx, LeaderboardKey, member)
+	provisionalCmd := pipe.ZScore(ctx, ProvisionalKey, member)
+	regionCmd := pipe.HGet(ctx, RegionsKey, member)
+	_, _ = pipe.Exec(ctx)
+	for _, cmd := range []redis.Cmder{globalCmd, provisionalCmd, regionCmd} {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			return fmt.Errorf("failed to get user placement: %w", err)
+		}
+	}
+
+	var score float64
+	tx := s.redis.TxPipeline()
+	switch {
+	case globalCmd.Err() == nil:
+		score = globalCmd.Val()
+		tx.ZRem(ctx, LeaderboardKey, member)
+		if region := regionCmd.Val(); region != "" {
+			tx.ZRem(ctx, RegionKey(region), member)
+		}
+	case provisionalCmd.Err() == nil:
+		score = provisionalCmd.Val()
+		tx.ZRem(ctx, ProvisionalKey, member)
+	default:
+		return nil
+	}
+	tx.ZAdd(ctx, ModeratedKey, &redis.Z{Score: score, Member: member})
+	if globalCmd.Err() == nil {
+		recordChange(ctx, tx, RatingChange{UserID: userID, OldRating: int(score)})
+	} else {
+		bump(ctx, tx)
+	}
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to moderate user: %w", err)
+	}
+
+	if globalCmd.Err() == nil {
+		s.notify(ctx, RatingChange{UserID: userID, OldRating: int(score)})
+	}
+	return nil
+}
+
+// Restore moves a moderated user back to the provisional set or the public
+// leaderboards. It is a no-op for users who are not moderated.
+func (s *RankingService) Restore(ctx context.Context, userID int64, provisional bool) error {
+	member := fmt.Sprintf("%d", userID)
+
+	pipe := s.redis.Pipeline()
+	scoreCmd := pipe.ZScore(ctx, ModeratedKey, member)
+	regionCmd := pipe.HGet(ctx, RegionsKey, member)
+	_, _ = pipe.Exec(ctx)
+	if err := scoreCmd.Err(); err != nil {
+		if err == redis.Nil {
+			return nil
+		}
+		return fmt.Errorf("failed to get moderated score: %w", err)
+	}
+	if err := regionCmd.Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to get user region: %w", err)
+	}
+
+	z := &redis.Z{Score: scoreCmd.Val(), Member: member}
+	tx := s.redis.TxPipeline()
+	tx.ZRem(ctx, ModeratedKey, member)
+	if provisional {
+		tx.ZAdd(ctx, ProvisionalKey, z)
+		bump(ctx, tx)
+	} else {
+		tx.ZAdd(ctx, LeaderboardKey, z)
+		if region := regionCmd.Val(); region != "" {
+			tx.ZAdd(ctx, RegionKey(region), z)
+		}
+		recordChange(ctx, tx, RatingChange{UserID: userID, NewRating: int(scoreCmd.Val())})
+	}
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	if !provisional {
+		s.notify(ctx, RatingChange{UserID: userID, NewRating: int(scoreCmd.Val())})
+	}
+	return nil
+}
+
+// ShadowRank returns the rank a moderated user would have on the global
+// leaderboard, without them counting towards anyone else's rank
+func (s *RankingService) ShadowRank(ctx context.Context, userID int64) (int, bool, error) {
+	var rank int
+	var ok bool
+	err := s.read(ctx, func() (err error) {
+		rank, ok, err = s.redisShadowRank(ctx, userID)
+		return err
+	}, func() (err error) {
+		rank, ok, err = s.fallback.ShadowRank(ctx, userID, false)
+		return err
+	})
+	return rank, ok, err
+}
+
+// RegionalShadowRank returns the rank a moderated user would have within
+// their region; ok is false if they are not moderated or have no region
+func (s *RankingService) RegionalShadowRank(ctx context.Context, userID int64) (int, bool, error) {
+	var rank int
+	var ok bool
+	err := s.read(ctx, func() (err error) {
+		rank, ok, err = s.redisRegionalShadowRank(ctx, userID)
+		return err
+	}, func() (err error) {
+		rank, ok, err = s.fallback.ShadowRank(ctx, userID, true)
+		return err
+	})
+	return rank, ok, err
+}
+
+func (s *RankingService) redisShadowRank(ctx context.Context, userID int64) (int, bool, error) {
+	score, err := s.redis.ZScore(ctx, ModeratedKey, fmt.Sprintf("%d", userID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get moderated score: %w", err)
+	}
+
+	rank, err := s.rankForScore(ctx, score)
+	if err != nil {
+		return 0, false, err
+	}
+	return rank, true, nil
+}
+
+func (s *RankingService) redisRegionalShadowRank(ctx context.Context, userID int64) (int, bool, error) {
+	member := fmt.Sprintf("%d", userID)
+
+	// Moderation keeps the user's region, only their regional score goes
+	pipe := s.redis.Pipeline()
+	scoreCmd := pipe.ZScore(ctx, ModeratedKey, member)
+	regionCmd := pipe.HGet(ctx, RegionsKey, member)
+	_, _ = pipe.Exec(ctx)
+	if err := scoreCmd.Err(); err != nil {
+		if err == redis.Nil {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get moderated score: %w", err)
+	}
+	if err := regionCmd.Err(); err != nil && err != redis.Nil {
+		return 0, false, fmt.Errorf("failed to get user region: %w", err)
+	}
+	region := regionCmd.Val()
+	if region == "" {
+		return 0, false, nil
+	}
+
+	higher, err := s.redis.ZCount(ctx, RegionKey(region), fmt.Sprintf("(%f", scoreCmd.Val()), "+inf").Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to count higher ratings: %w", err)
+	}
+	return int(higher) + 1, true, nil
+}
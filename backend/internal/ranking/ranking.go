@@ -3,31 +3,414 @@ package ranking
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
 const LeaderboardKey = "leaderboard:global"
 
+// RegionsKey is a hash of user ID to region code, used to keep the
+// per-region sorted sets in step with the global one
+const RegionsKey = "leaderboard:regions"
+
+// ProvisionalKey holds provisional users' ratings, kept out of the global
+// and regional sets until the user is promoted
+const ProvisionalKey = "leaderboard:provisional"
+
+// VersionsKey is a hash of user ID to the row version of the last rating
+// written to Redis
+const VersionsKey = "leaderboard:versions"
+
+// RegionKey returns the sorted set key for a region's leaderboard
+func RegionKey(region string) string {
+	return "leaderboard:region:" + region
+}
+
 type RankingService struct {
-	redis *redis.Client
+	redis     *redis.Client
+	mu        sync.RWMutex
+	listeners []RatingListener
+
+	// fallback serves reads while breaker reports Redis as unavailable
+	fallback Fallback
+	breaker  *Breaker
 }
 
+// RatingChange describes a change to a user's rating on the global leaderboard
+type RatingChange struct {
+	UserID    int64
+	OldRating int // 0 if the user was not on the leaderboard before
+	NewRating int // 0 if the user was removed from the leaderboard
+}
+
+// RatingListener is notified after a global leaderboard change has been
+// written to Redis. Provisional users' ratings don't trigger listeners until
+// they are promoted.
+type RatingListener func(ctx context.Context, change RatingChange)
+
 func NewRankingService(redis *redis.Client) *RankingService {
 	return &RankingService{redis: redis}
 }
 
-// UpdateUserRating updates a user's rating in the Redis sorted set
-func (s *RankingService) UpdateUserRating(ctx context.Context, userID int64, rating int) error {
-	return s.redis.ZAdd(ctx, LeaderboardKey, &redis.Z{
+// AddListener registers a callback that runs after every global leaderboard change
+func (s *RankingService) AddListener(listener RatingListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// updateScript writes a rating to whichever set the user lives in: the
+// moderated or provisional set, or the global set and their regional set.
+// A positive version is compared with the last one written for the user and
+// stale writes are dropped, so Redis ends up in the same order as PostgreSQL.
+// Every applied write bumps the board version, and global leaderboard writes
+// are appended to the change stream under it.
+//
+// KEYS: global set, provisional set, moderated set, regions hash, versions hash,
+// board version, board modified time, change stream, trimmed marker
+// ARGV: user ID, rating, version, regional key prefix, current Unix millis,
+// change stream length
+// Returns -2 for a stale write, -1 if the user is not on the global
+// leaderboard, otherwise their previous global score (0 if they were new).
+var updateScript = redis.NewScript(appendChangeLua + `
+local version = tonumber(ARGV[3])
+if version > 0 then
+	local current = tonumber(redis.call('HGET', KEYS[5], ARGV[1]) or '0')
+	if current >= version then
+		return -2
+	end
+	redis.call('HSET', KEYS[5], ARGV[1], version)
+end
+local seq = redis.call('INCR', KEYS[6])
+redis.call('SET', KEYS[7], ARGV[5])
+
+if redis.call('ZSCORE', KEYS[3], ARGV[1]) then
+	redis.call('ZADD', KEYS[3], ARGV[2], ARGV[1])
+	return -1
+end
+if redis.call('ZSCORE', KEYS[2], ARGV[1]) then
+	redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+	return -1
+end
+
+local old = redis.call('ZSCORE', KEYS[1], ARGV[1])
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+local region = redis.call('HGET', KEYS[4], ARGV[1])
+if region then
+	redis.call('ZADD', ARGV[4] .. region, ARGV[2], ARGV[1])
+end
+append_change(KEYS[8], KEYS[9], seq, ARGV[1], old or 0, ARGV[2], ARGV[5], ARGV[6])
+if old then
+	return tonumber(old)
+end
+return 0
+`)
+
+var updateKeys = []string{LeaderboardKey, ProvisionalKey, ModeratedKey, RegionsKey, VersionsKey, BoardVersionKey, BoardModifiedKey, ChangesKey, ChangesTrimmedKey}
+
+// UpdateUserRating updates a user's rating in the Redis sorted sets. version
+// is the user's row version after the update; pass 0 to write unconditionally.
+func (s *RankingService) UpdateUserRating(ctx context.Context, userID int64, rating int, version int64) error {
+	old, err := updateScript.Run(ctx, s.redis, updateKeys,
+		fmt.Sprintf("%d", userID), rating, version, RegionKey(""), time.Now().UnixMilli(), MaxChanges,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to update rating: %w", err)
+	}
+
+	// Provisional, moderated and stale writes don't change the public board
+	if old < 0 {
+		return nil
+	}
+	s.notify(ctx, RatingChange{UserID: userID, OldRating: old, NewRating: rating})
+	return nil
+}
+
+// RatingWrite is one entry of a batch passed to UpdateUserRatings
+type RatingWrite struct {
+	UserID  int64
+	Rating  int
+	Version int64
+}
+
+// UpdateUserRatings applies UpdateUserRating to every write in one pipeline
+func (s *RankingService) UpdateUserRatings(ctx context.Context, writes []RatingWrite) error {
+	if len(writes) == 0 {
+		return nil
+	}
+
+	// EVALSHA inside a pipeline can't fall back to EVAL, so load the script first
+	if err := updateScript.Load(ctx, s.redis).Err(); err != nil {
+		return fmt.Errorf("failed to load rating script: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	pipe := s.redis.Pipeline()
+	cmds := make([]*redis.Cmd, len(writes))
+	for i, w := range writes {
+		cmds[i] = updateScript.EvalSha(ctx, pipe, updateKeys, fmt.Sprintf("%d", w.UserID), w.Rating, w.Version, RegionKey(""), now, MaxChanges)
+	}
+	// Exec reports the first failed command; the writes that did succeed still
+	// need their listeners notified
+	_, _ = pipe.Exec(ctx)
+
+	var firstErr error
+	for i, w := range writes {
+		old, err := cmds[i].Int()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to update rating for user %d: %w", w.UserID, err)
+			}
+			continue
+		}
+		if old >= 0 {
+			s.notify(ctx, RatingChange{UserID: w.UserID, OldRating: old, NewRating: w.Rating})
+		}
+	}
+	return firstErr
+}
+
+func (s *RankingService) notify(ctx context.Context, change RatingChange) {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(ctx, change)
+	}
+}
+
+// AddProvisional places a new user in the provisional set instead of the
+// public leaderboards
+func (s *RankingService) AddProvisional(ctx context.Context, userID int64, rating int) error {
+	tx := s.redis.TxPipeline()
+	tx.ZAdd(ctx, ProvisionalKey, &redis.Z{
 		Score:  float64(rating),
 		Member: fmt.Sprintf("%d", userID),
-	}).Err()
+	})
+	bump(ctx, tx)
+	_, err := tx.Exec(ctx)
+	return err
+}
+
+// promoteScript moves a provisional user onto the global set and their
+// regional set. Reading the score and moving it happen in one step, so a
+// rating written in between can't be lost.
+//
+// KEYS: provisional set, global set, regions hash, board version, board
+// modified time, change stream, trimmed marker
+// ARGV: user ID, regional key prefix, current Unix millis, change stream length
+// Returns the user's rating, or -1 if they are not provisional.
+var promoteScript = redis.NewScript(appendChangeLua + `
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not score then
+	return -1
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZADD', KEYS[2], score, ARGV[1])
+local region = redis.call('HGET', KEYS[3], ARGV[1])
+if region then
+	redis.call('ZADD', ARGV[2] .. region, score, ARGV[1])
+end
+local seq = redis.call('INCR', KEYS[4])
+redis.call('SET', KEYS[5], ARGV[3])
+append_change(KEYS[6], KEYS[7], seq, ARGV[1], 0, score, ARGV[3], ARGV[4])
+return tonumber(score)
+`)
+
+// Promote moves a provisional user onto the global and regional leaderboards.
+// It is a no-op for users who are not provisional.
+func (s *RankingService) Promote(ctx context.Context, userID int64) error {
+	rating, err := promoteScript.Run(ctx, s.redis,
+		[]string{ProvisionalKey, LeaderboardKey, RegionsKey, BoardVersionKey, BoardModifiedKey, ChangesKey, ChangesTrimmedKey},
+		fmt.Sprintf("%d", userID), RegionKey(""), time.Now().UnixMilli(), MaxChanges,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to promote user: %w", err)
+	}
+	if rating < 0 {
+		return nil
+	}
+
+	s.notify(ctx, RatingChange{UserID: userID, NewRating: rating})
+	return nil
+}
+
+// RemoveUser deletes a user from every leaderboard set and the region hash
+func (s *RankingService) RemoveUser(ctx context.Context, userID int64) error {
+	member := fmt.Sprintf("%d", userID)
+
+	pipe := s.redis.Pipeline()
+	scoreCmd := pipe.ZScore(ctx, LeaderboardKey, member)
+	regionCmd := pipe.HGet(ctx, RegionsKey, member)
+	_, _ = pipe.Exec(ctx)
+	for _, cmd := range []redis.Cmder{scoreCmd, regionCmd} {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			return fmt.Errorf("failed to get user placement: %w", err)
+		}
+	}
+
+	tx := s.redis.TxPipeline()
+	tx.ZRem(ctx, LeaderboardKey, member)
+	tx.ZRem(ctx, ProvisionalKey, member)
+	tx.ZRem(ctx, ModeratedKey, member)
+	if region := regionCmd.Val(); region != "" {
+		tx.ZRem(ctx, RegionKey(region), member)
+	}
+	tx.HDel(ctx, RegionsKey, member)
+	tx.HDel(ctx, VersionsKey, member)
+	if scoreCmd.Err() == nil {
+		recordChange(ctx, tx, RatingChange{UserID: userID, OldRating: int(scoreCmd.Val())})
+	} else {
+		bump(ctx, tx)
+	}
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove user: %w", err)
+	}
+
+	if scoreCmd.Err() == nil {
+		s.notify(ctx, RatingChange{UserID: userID, OldRating: int(scoreCmd.Val())})
+	}
+	return nil
+}
+
+// GetRating returns a user's rating from the global or provisional set
+func (s *RankingService) GetRating(ctx context.Context, userID int64) (int, bool, error) {
+	member := fmt.Sprintf("%d", userID)
+
+	pipe := s.redis.Pipeline()
+	globalCmd := pipe.ZScore(ctx, LeaderboardKey, member)
+	provisionalCmd := pipe.ZScore(ctx, ProvisionalKey, member)
+	_, _ = pipe.Exec(ctx)
+	for _, cmd := range []*redis.FloatCmd{globalCmd, provisionalCmd} {
+		if err := cmd.Err(); err == nil {
+			return int(cmd.Val()), true, nil
+		} else if err != redis.Nil {
+			return 0, false, fmt.Errorf("failed to get user score: %w", err)
+		}
+	}
+	return 0, false, nil
+}
+
+// Count returns the number of users on the leaderboard
+func (s *RankingService) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.read(ctx, func() (err error) {
+		count, err = s.redisCount(ctx)
+		return err
+	}, func() (err error) {
+		_, _, count, err = s.fallback.CountRatings(ctx, 0)
+		return err
+	})
+	return count, err
+}
+
+func (s *RankingService) redisCount(ctx context.Context) (int, error) {
+	count, err := s.redis.ZCard(ctx, LeaderboardKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count leaderboard: %w", err)
+	}
+	return int(count), nil
+}
+
+// CountInRanges counts users per inclusive [min, max] rating range in one pipeline
+func (s *RankingService) CountInRanges(ctx context.Context, ranges [][2]int) ([]int, error) {
+	var counts []int
+	err := s.read(ctx, func() (err error) {
+		counts, err = s.redisCountInRanges(ctx, ranges)
+		return err
+	}, func() (err error) {
+		counts, err = s.fallback.CountInRanges(ctx, ranges)
+		return err
+	})
+	return counts, err
+}
+
+func (s *RankingService) redisCountInRanges(ctx context.Context, ranges [][2]int) ([]int, error) {
+	pipe := s.redis.Pipeline()
+	cmds := make([]*redis.IntCmd, len(ranges))
+	for i, r := range ranges {
+		cmds[i] = pipe.ZCount(ctx, LeaderboardKey, strconv.Itoa(r[0]), strconv.Itoa(r[1]))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to count rating ranges: %w", err)
+	}
+
+	counts := make([]int, len(ranges))
+	for i, cmd := range cmds {
+		counts[i] = int(cmd.Val())
+	}
+	return counts, nil
+}
+
+// CountBetween counts users rated strictly between two ratings
+func (s *RankingService) CountBetween(ctx context.Context, low, high int) (int, error) {
+	if low > high {
+		low, high = high, low
+	}
+	if high-low < 2 {
+		return 0, nil
+	}
+	// Ratings are whole numbers, so strictly between is the inclusive range
+	// one in from each end
+	counts, err := s.CountInRanges(ctx, [][2]int{{low + 1, high - 1}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ratings between: %w", err)
+	}
+	return counts[0], nil
+}
+
+// RatingAtPosition returns the rating at a zero-based position in descending order
+func (s *RankingService) RatingAtPosition(ctx context.Context, position int) (int, bool, error) {
+	var rating int
+	var ok bool
+	err := s.read(ctx, func() (err error) {
+		rating, ok, err = s.redisRatingAtPosition(ctx, position)
+		return err
+	}, func() (err error) {
+		rating, ok, err = s.fallback.RatingAtPosition(ctx, position)
+		return err
+	})
+	return rating, ok, err
+}
+
+func (s *RankingService) redisRatingAtPosition(ctx context.Context, position int) (int, bool, error) {
+	results, err := s.redis.ZRevRangeWithScores(ctx, LeaderboardKey, int64(position), int64(position)).Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get rating at position: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, false, nil
+	}
+	return int(results[0].Score), true, nil
 }
 
 // GetRank calculates the tie-aware rank for a user
 // Rank = number of users with rating > user's rating + 1
 func (s *RankingService) GetRank(ctx context.Context, userID int64) (int, error) {
+	var rank int
+	err := s.read(ctx, func() (err error) {
+		rank, err = s.redisRank(ctx, userID)
+		return err
+	}, func() error {
+		ranks, err := s.fallback.RanksForUsers(ctx, []int64{userID}, false)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if rank, ok = ranks[userID]; !ok {
+			return fmt.Errorf("user not found in leaderboard")
+		}
+		return nil
+	})
+	return rank, err
+}
+
+func (s *RankingService) redisRank(ctx context.Context, userID int64) (int, error) {
 	// Get user's rating (convert userID to string for Redis)
 	score, err := s.redis.ZScore(ctx, LeaderboardKey, fmt.Sprintf("%d", userID)).Result()
 	if err != nil {
@@ -37,7 +420,54 @@ func (s *RankingService) GetRank(ctx context.Context, userID int64) (int, error)
 		return 0, fmt.Errorf("failed to get user score: %w", err)
 	}
 
-	// Count users with rating > user's rating
+	return s.rankForScore(ctx, score)
+}
+
+// RankForRating returns the tie-aware rank a rating would have on the
+// leaderboard, and the percentage of players it would be strictly above
+func (s *RankingService) RankForRating(ctx context.Context, rating int) (int, float64, error) {
+	var rank int
+	var percentile float64
+	err := s.read(ctx, func() (err error) {
+		rank, percentile, err = s.redisRankForRating(ctx, rating)
+		return err
+	}, func() error {
+		higher, lower, total, err := s.fallback.CountRatings(ctx, rating)
+		if err != nil {
+			return err
+		}
+		rank, percentile = higher+1, 0
+		if total > 0 {
+			percentile = float64(lower) / float64(total) * 100
+		}
+		return nil
+	})
+	return rank, percentile, err
+}
+
+func (s *RankingService) redisRankForRating(ctx context.Context, rating int) (int, float64, error) {
+	rank, err := s.rankForScore(ctx, float64(rating))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	pipe := s.redis.Pipeline()
+	totalCmd := pipe.ZCard(ctx, LeaderboardKey)
+	lowerCmd := pipe.ZCount(ctx, LeaderboardKey, "-inf", fmt.Sprintf("(%d", rating))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to count lower ratings: %w", err)
+	}
+
+	percentile := 0.0
+	if total := totalCmd.Val(); total > 0 {
+		percentile = float64(lowerCmd.Val()) / float64(total) * 100
+	}
+	return rank, percentile, nil
+}
+
+// rankForScore counts users with a strictly higher score
+func (s *RankingService) rankForScore(ctx context.Context, score float64) (int, error) {
+	// Count users with rating > score
 	// Using ZCount with (score, +inf) to count higher ratings
 	higherCount, err := s.redis.ZCount(ctx, LeaderboardKey,
 		fmt.Sprintf("(%f", score), "+inf").Result()
@@ -51,6 +481,18 @@ func (s *RankingService) GetRank(ctx context.Context, userID int64) (int, error)
 
 // GetRanksForUsers gets ranks for multiple users efficiently
 func (s *RankingService) GetRanksForUsers(ctx context.Context, userIDs []int64) (map[int64]int, error) {
+	var ranks map[int64]int
+	err := s.read(ctx, func() (err error) {
+		ranks, err = s.redisRanksForUsers(ctx, userIDs)
+		return err
+	}, func() (err error) {
+		ranks, err = s.fallback.RanksForUsers(ctx, userIDs, false)
+		return err
+	})
+	return ranks, err
+}
+
+func (s *RankingService) redisRanksForUsers(ctx context.Context, userIDs []int64) (map[int64]int, error) {
 	ranks := make(map[int64]int)
 	
 	// Use pipeline for batch operations
@@ -98,41 +540,118 @@ func (s *RankingService) GetRanksForUsers(ctx context.Context, userIDs []int64)
 
 // GetLeaderboard gets top N users with their ranks
 func (s *RankingService) GetLeaderboard(ctx context.Context, limit, offset int) ([]LeaderboardEntry, error) {
+	return s.getLeaderboard(ctx, "", limit, offset)
+}
+
+// GetRegionalLeaderboard gets top N users of a region with their regional ranks
+func (s *RankingService) GetRegionalLeaderboard(ctx context.Context, region string, limit, offset int) ([]LeaderboardEntry, error) {
+	return s.getLeaderboard(ctx, region, limit, offset)
+}
+
+func (s *RankingService) getLeaderboard(ctx context.Context, region string, limit, offset int) ([]LeaderboardEntry, error) {
+	key := LeaderboardKey
+	if region != "" {
+		key = RegionKey(region)
+	}
+
+	var entries []LeaderboardEntry
+	err := s.read(ctx, func() (err error) {
+		entries, err = s.redisLeaderboard(ctx, key, limit, offset)
+		return err
+	}, func() (err error) {
+		entries, err = s.fallback.LeaderboardPage(ctx, region, math.MinInt32, math.MaxInt32, limit, offset)
+		return err
+	})
+	return entries, err
+}
+
+func (s *RankingService) redisLeaderboard(ctx context.Context, key string, limit, offset int) ([]LeaderboardEntry, error) {
 	// Get users from Redis sorted set (descending order)
-	results, err := s.redis.ZRevRangeWithScores(ctx, LeaderboardKey, int64(offset), int64(offset+limit-1)).Result()
+	results, err := s.redis.ZRevRangeWithScores(ctx, key, int64(offset), int64(offset+limit-1)).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
 	}
 
+	return s.rankSlice(ctx, key, results, offset)
+}
+
+// GetLeaderboardByRating gets users with minRating <= rating <= maxRating in
+// descending order, numbered with their tie-aware ranks. An empty region
+// means the global leaderboard.
+func (s *RankingService) GetLeaderboardByRating(ctx context.Context, region string, minRating, maxRating, limit, offset int) ([]LeaderboardEntry, error) {
+	var entries []LeaderboardEntry
+	err := s.read(ctx, func() (err error) {
+		entries, err = s.redisLeaderboardByRating(ctx, region, minRating, maxRating, limit, offset)
+		return err
+	}, func() (err error) {
+		entries, err = s.fallback.LeaderboardPage(ctx, region, minRating, maxRating, limit, offset)
+		return err
+	})
+	return entries, err
+}
+
+func (s *RankingService) redisLeaderboardByRating(ctx context.Context, region string, minRating, maxRating, limit, offset int) ([]LeaderboardEntry, error) {
+	key := LeaderboardKey
+	if region != "" {
+		key = RegionKey(region)
+	}
+
+	results, err := s.redis.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:    strconv.Itoa(minRating),
+		Max:    strconv.Itoa(maxRating),
+		Offset: int64(offset),
+		Count:  int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard by rating: %w", err)
+	}
+
+	return s.rankSlice(ctx, key, results, -1)
+}
+
+// rankSlice assigns tie-aware ranks to a contiguous slice of the sorted set
+// that begins at zero-based position start (-1 if unknown). The first entry is
+// ranked with ZCOUNT so a slice starting in the middle of a tie group gets the
+// group's rank; later groups are ranked by global position.
+func (s *RankingService) rankSlice(ctx context.Context, key string, results []redis.Z, start int) ([]LeaderboardEntry, error) {
 	if len(results) == 0 {
 		return []LeaderboardEntry{}, nil
 	}
 
-	// Calculate ranks with tie-awareness
-	entries := make([]LeaderboardEntry, 0, len(results))
-	currentRank := offset + 1
-	prevRating := float64(-1)
+	first := results[0]
+	currentRank := 1
+	if start != 0 {
+		pipe := s.redis.Pipeline()
+		var positionCmd *redis.IntCmd
+		if start < 0 {
+			positionCmd = pipe.ZRevRank(ctx, key, fmt.Sprintf("%v", first.Member))
+		}
+		higherCmd := pipe.ZCount(ctx, key, fmt.Sprintf("(%f", first.Score), "+inf")
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to rank leaderboard slice: %w", err)
+		}
+		if positionCmd != nil {
+			start = int(positionCmd.Val())
+		}
+		currentRank = int(higherCmd.Val()) + 1
+	}
+	prevRating := first.Score
 
+	entries := make([]LeaderboardEntry, 0, len(results))
 	for i, result := range results {
-		rating := result.Score
-		
-		// If rating changed, update rank
-		if prevRating == -1 || rating != prevRating {
-			// New rating group - rank is position in list (offset + index + 1)
-			currentRank = offset + i + 1
-			prevRating = rating
+		if result.Score != prevRating {
+			currentRank = start + i + 1
+			prevRating = result.Score
 		}
-		// If rating is same as previous, keep the same rank (tie-aware)
 
-		var userID int64
-		count, err := fmt.Sscanf(fmt.Sprintf("%v", result.Member), "%d", &userID)
-		if err != nil || count != 1 {
+		userID, err := strconv.ParseInt(fmt.Sprintf("%v", result.Member), 10, 64)
+		if err != nil {
 			continue
 		}
 
 		entries = append(entries, LeaderboardEntry{
 			UserID: userID,
-			Rating: int(rating),
+			Rating: int(result.Score),
 			Rank:   currentRank,
 		})
 	}
@@ -140,6 +659,70 @@ func (s *RankingService) GetLeaderboard(ctx context.Context, limit, offset int)
 	return entries, nil
 }
 
+// RankAmong ranks a group of users against each other using their scores from
+// the global sorted set (one ZMSCORE call, no scan). Rank is relative to the
+// group and tie-aware; users not on the leaderboard are omitted.
+func (s *RankingService) RankAmong(ctx context.Context, userIDs []int64) ([]LeaderboardEntry, error) {
+	if len(userIDs) == 0 {
+		return []LeaderboardEntry{}, nil
+	}
+
+	var ratings map[int64]int
+	err := s.read(ctx, func() (err error) {
+		ratings, err = s.redisRatings(ctx, userIDs)
+		return err
+	}, func() (err error) {
+		ratings, err = s.fallback.Ratings(ctx, userIDs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(ratings))
+	for userID, rating := range ratings {
+		entries = append(entries, LeaderboardEntry{UserID: userID, Rating: rating})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Rating != entries[j].Rating {
+			return entries[i].Rating > entries[j].Rating
+		}
+		return entries[i].UserID < entries[j].UserID
+	})
+
+	for i := range entries {
+		if i > 0 && entries[i].Rating == entries[i-1].Rating {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *RankingService) redisRatings(ctx context.Context, userIDs []int64) (map[int64]int, error) {
+	members := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		members[i] = fmt.Sprintf("%d", userID)
+	}
+
+	scores, err := s.redis.ZMScore(ctx, LeaderboardKey, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scores: %w", err)
+	}
+
+	ratings := make(map[int64]int, len(userIDs))
+	for i, score := range scores {
+		if score == 0 {
+			continue
+		}
+		ratings[userIDs[i]] = int(score)
+	}
+	return ratings, nil
+}
+
 type LeaderboardEntry struct {
 	UserID int64
 	Rating int
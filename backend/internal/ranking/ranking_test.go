@@ -0,0 +1,258 @@
+package ranking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestService(t *testing.T) (*RankingService, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRankingService(client), server
+}
+
+// setRatings writes ratings keyed by user ID, failing the test on error
+func setRatings(t *testing.T, s *RankingService, ratings map[int64]int) {
+	t.Helper()
+	for userID, rating := range ratings {
+		if err := s.UpdateUserRating(context.Background(), userID, rating, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTieAwareRanks(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	setRatings(t, s, map[int64]int{1: 2000, 2: 1800, 3: 1800, 4: 1500})
+
+	ranks, err := s.GetRanksForUsers(ctx, []int64{1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]int{1: 1, 2: 2, 3: 2, 4: 4}
+	if len(ranks) != len(want) {
+		t.Fatalf("ranks = %v, want %v", ranks, want)
+	}
+	for userID, rank := range want {
+		if ranks[userID] != rank {
+			t.Errorf("rank of %d = %d, want %d", userID, ranks[userID], rank)
+		}
+	}
+
+	entries, err := s.GetLeaderboard(ctx, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Rank != 2 || entries[1].Rank != 4 {
+		t.Errorf("page 2 = %+v, want ranks 2 and 4", entries)
+	}
+}
+
+func TestRankAmong(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	setRatings(t, s, map[int64]int{1: 2400, 2: 1800, 3: 2000, 4: 1800, 5: 3000})
+
+	// User 5 is ahead of everyone but isn't in the group; user 6 is unranked
+	entries, err := s.RankAmong(ctx, []int64{4, 2, 6, 3, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LeaderboardEntry{
+		{UserID: 1, Rating: 2400, Rank: 1},
+		{UserID: 3, Rating: 2000, Rank: 2},
+		{UserID: 2, Rating: 1800, Rank: 3},
+		{UserID: 4, Rating: 1800, Rank: 3},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entries[%d] = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	if entries, err := s.RankAmong(ctx, nil); err != nil || len(entries) != 0 {
+		t.Errorf("RankAmong(nil) = %v, %v; want no entries", entries, err)
+	}
+}
+
+func TestRankForRating(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	if rank, percentile, err := s.RankForRating(ctx, 1500); err != nil || rank != 1 || percentile != 0 {
+		t.Errorf("on an empty board = %d, %v, %v; want 1, 0", rank, percentile, err)
+	}
+
+	setRatings(t, s, map[int64]int{1: 2000, 2: 1800, 3: 1800, 4: 1500})
+	tests := []struct {
+		rating     int
+		rank       int
+		percentile float64
+	}{
+		{rating: 2500, rank: 1, percentile: 100},
+		{rating: 1800, rank: 2, percentile: 25},
+		{rating: 1700, rank: 4, percentile: 25},
+		{rating: 100, rank: 5, percentile: 0},
+	}
+	for _, tt := range tests {
+		rank, percentile, err := s.RankForRating(ctx, tt.rating)
+		if err != nil || rank != tt.rank || percentile != tt.percentile {
+			t.Errorf("RankForRating(%d) = %d, %v, %v; want %d, %v", tt.rating, rank, percentile, err, tt.rank, tt.percentile)
+		}
+	}
+}
+
+func TestGetLeaderboardByRating(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	if err := s.SetNewUserRegions(ctx, map[int64]string{2: "DE", 3: "DE", 4: "DE"}); err != nil {
+		t.Fatal(err)
+	}
+	setRatings(t, s, map[int64]int{1: 2500, 2: 2000, 3: 1800, 4: 1800, 5: 1200})
+
+	tests := []struct {
+		name               string
+		region             string
+		min, max, lim, off int
+		wantIDs            []int64
+		wantRanks          []int
+	}{
+		{name: "global", min: 1500, max: 2200, lim: 10, wantIDs: []int64{2, 4, 3}, wantRanks: []int{2, 3, 3}},
+		{name: "inside a tie", min: 1500, max: 2200, lim: 10, off: 2, wantIDs: []int64{3}, wantRanks: []int{3}},
+		{name: "regional", region: "DE", min: 1800, max: 1900, lim: 10, wantIDs: []int64{4, 3}, wantRanks: []int{2, 2}},
+		{name: "empty", min: 3000, max: 5000, lim: 10},
+	}
+	for _, tt := range tests {
+		entries, err := s.GetLeaderboardByRating(ctx, tt.region, tt.min, tt.max, tt.lim, tt.off)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(entries) != len(tt.wantIDs) {
+			t.Errorf("%s: entries = %+v, want users %v", tt.name, entries, tt.wantIDs)
+			continue
+		}
+		for i, entry := range entries {
+			if entry.UserID != tt.wantIDs[i] || entry.Rank != tt.wantRanks[i] {
+				t.Errorf("%s: entries[%d] = %+v, want user %d ranked %d", tt.name, i, entry, tt.wantIDs[i], tt.wantRanks[i])
+			}
+		}
+	}
+}
+
+func TestPromote(t *testing.T) {
+	s, server := newTestService(t)
+	ctx := context.Background()
+
+	var changes []RatingChange
+	s.AddListener(func(ctx context.Context, change RatingChange) {
+		changes = append(changes, change)
+	})
+
+	if err := s.SetNewUserRegions(ctx, map[int64]string{1: "DE"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddProvisional(ctx, 1, 1500); err != nil {
+		t.Fatal(err)
+	}
+	// Rated again while provisional: the new rating is the one promoted
+	if err := s.UpdateUserRating(ctx, 1, 1650, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ranks, _ := s.GetRanksForUsers(ctx, []int64{1}); len(ranks) != 0 {
+		t.Fatalf("provisional user is ranked: %v", ranks)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("provisional writes notified listeners: %+v", changes)
+	}
+
+	if err := s.Promote(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists(ProvisionalKey) {
+		t.Error("user still in the provisional set")
+	}
+	for _, key := range []string{LeaderboardKey, RegionKey("DE")} {
+		if score, err := server.ZScore(key, "1"); err != nil || score != 1650 {
+			t.Errorf("%s score = %v, %v; want 1650", key, score, err)
+		}
+	}
+	if len(changes) != 1 || changes[0] != (RatingChange{UserID: 1, NewRating: 1650}) {
+		t.Errorf("changes = %+v, want user 1 joining at 1650", changes)
+	}
+	entries, err := server.Stream(ChangesKey)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("change stream = %+v, %v; want one entry", entries, err)
+	}
+
+	// Promoting again is a no-op
+	if err := s.Promote(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("second promotion notified listeners: %+v", changes)
+	}
+}
+
+func TestStaleVersionIsDropped(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	var changes []RatingChange
+	s.AddListener(func(ctx context.Context, change RatingChange) {
+		changes = append(changes, change)
+	})
+
+	if err := s.UpdateUserRating(ctx, 1, 1500, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateUserRating(ctx, 1, 1400, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	rating, ok, err := s.GetRating(ctx, 1)
+	if err != nil || !ok || rating != 1500 {
+		t.Errorf("GetRating = %d, %v, %v; want 1500 after a stale write", rating, ok, err)
+	}
+	if len(changes) != 1 || changes[0] != (RatingChange{UserID: 1, OldRating: 0, NewRating: 1500}) {
+		t.Errorf("listener saw %+v, want only the first write", changes)
+	}
+}
+
+func TestChangeStreamTrim(t *testing.T) {
+	s, server := newTestService(t)
+	ctx := context.Background()
+	keys := []string{BoardVersionKey, BoardModifiedKey, ChangesKey, ChangesTrimmedKey}
+
+	// A stream capped at three keeps the newest three and records the
+	// newest dropped version
+	for i := 1; i <= 5; i++ {
+		if err := changeScript.Run(ctx, s.redis, keys, i, 0, 1500, 0, 3).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := server.Stream(ChangesKey)
+	if err != nil || len(entries) != 3 || entries[0].ID != "3-0" {
+		t.Fatalf("change stream = %+v, %v; want versions 3 to 5", entries, err)
+	}
+	if trimmed, _ := server.Get(ChangesTrimmedKey); trimmed != "2" {
+		t.Errorf("trimmed marker = %q, want 2", trimmed)
+	}
+
+	// A change that can't be appended is recorded as lost
+	server.Set(BoardVersionKey, "3")
+	if err := changeScript.Run(ctx, s.redis, keys, 6, 0, 1500, 0, 3).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if trimmed, _ := server.Get(ChangesTrimmedKey); trimmed != "4" {
+		t.Errorf("trimmed marker after a lost change = %q, want 4", trimmed)
+	}
+}
@@ -0,0 +1,144 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ParseRegion normalizes an optional ISO 3166-1 alpha-2 country code
+func ParseRegion(value string) (string, bool) {
+	region := strings.ToUpper(strings.TrimSpace(value))
+	if region == "" {
+		return "", true
+	}
+	if len(region) != 2 || region[0] < 'A' || region[0] > 'Z' || region[1] < 'A' || region[1] > 'Z' {
+		return "", false
+	}
+	return region, true
+}
+
+// SetUserRegion records a user's region and moves their score between
+// regional sorted sets. An empty region removes the user from regional boards.
+func (s *RankingService) SetUserRegion(ctx context.Context, userID int64, region string) error {
+	member := fmt.Sprintf("%d", userID)
+
+	pipe := s.redis.Pipeline()
+	oldCmd := pipe.HGet(ctx, RegionsKey, member)
+	scoreCmd := pipe.ZScore(ctx, LeaderboardKey, member)
+	_, _ = pipe.Exec(ctx)
+	if err := oldCmd.Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to get user region: %w", err)
+	}
+	if err := scoreCmd.Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to get user score: %w", err)
+	}
+
+	tx := s.redis.TxPipeline()
+	if old := oldCmd.Val(); old != "" && old != region {
+		tx.ZRem(ctx, RegionKey(old), member)
+	}
+	if region == "" {
+		tx.HDel(ctx, RegionsKey, member)
+	} else {
+		tx.HSet(ctx, RegionsKey, member, region)
+		if scoreCmd.Err() == nil {
+			tx.ZAdd(ctx, RegionKey(region), &redis.Z{Score: scoreCmd.Val(), Member: member})
+		}
+	}
+	bump(ctx, tx)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set user region: %w", err)
+	}
+	return nil
+}
+
+// SetNewUserRegions records regions for users who aren't on any leaderboard
+// yet, so their first rating lands in the regional set too. Existing users
+// must go through SetUserRegion to be moved between regional sets.
+func (s *RankingService) SetNewUserRegions(ctx context.Context, regions map[int64]string) error {
+	fields := make(map[string]interface{}, len(regions))
+	for userID, region := range regions {
+		if region != "" {
+			fields[fmt.Sprintf("%d", userID)] = region
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := s.redis.HSet(ctx, RegionsKey, fields).Err(); err != nil {
+		return fmt.Errorf("failed to set user regions: %w", err)
+	}
+	return nil
+}
+
+// GetRegionalRanksForUsers gets each user's tie-aware rank within their own
+// region. Users without a region or score are omitted.
+func (s *RankingService) GetRegionalRanksForUsers(ctx context.Context, userIDs []int64) (map[int64]int, error) {
+	var ranks map[int64]int
+	err := s.read(ctx, func() (err error) {
+		ranks, err = s.redisRegionalRanksForUsers(ctx, userIDs)
+		return err
+	}, func() (err error) {
+		ranks, err = s.fallback.RanksForUsers(ctx, userIDs, true)
+		return err
+	})
+	return ranks, err
+}
+
+func (s *RankingService) redisRegionalRanksForUsers(ctx context.Context, userIDs []int64) (map[int64]int, error) {
+	ranks := make(map[int64]int)
+	if len(userIDs) == 0 {
+		return ranks, nil
+	}
+
+	members := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		members[i] = fmt.Sprintf("%d", userID)
+	}
+
+	pipe := s.redis.Pipeline()
+	regionsCmd := pipe.HMGet(ctx, RegionsKey, members...)
+	scoresCmd := pipe.ZMScore(ctx, LeaderboardKey, members...)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to execute pipeline: %w", err)
+	}
+
+	// Group users by region and rating so each rank is counted once
+	type group struct {
+		region string
+		score  float64
+	}
+	groups := make(map[group][]int64)
+	scores := scoresCmd.Val()
+	for i, value := range regionsCmd.Val() {
+		region, ok := value.(string)
+		if !ok || region == "" || scores[i] == 0 {
+			continue
+		}
+		key := group{region: region, score: scores[i]}
+		groups[key] = append(groups[key], userIDs[i])
+	}
+
+	pipe = s.redis.Pipeline()
+	cmds := make(map[group]*redis.IntCmd, len(groups))
+	for g := range groups {
+		cmds[g] = pipe.ZCount(ctx, RegionKey(g.region), fmt.Sprintf("(%f", g.score), "+inf")
+	}
+	if len(cmds) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to count higher ratings: %w", err)
+		}
+	}
+
+	for g, ids := range groups {
+		rank := int(cmds[g].Val()) + 1
+		for _, userID := range ids {
+			ranks[userID] = rank
+		}
+	}
+
+	return ranks, nil
+}
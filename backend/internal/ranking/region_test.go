@@ -0,0 +1,68 @@
+package ranking
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseRegion(t *testing.T) {
+	for value, want := range map[string]string{"": "", " de ": "DE", "us": "US"} {
+		if got, ok := ParseRegion(value); !ok || got != want {
+			t.Errorf("ParseRegion(%q) = %q, %v; want %q", value, got, ok, want)
+		}
+	}
+	for _, value := range []string{"USA", "U1", "é"} {
+		if _, ok := ParseRegion(value); ok {
+			t.Errorf("ParseRegion(%q) accepted an invalid region", value)
+		}
+	}
+}
+
+func TestRegionalRanks(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	if err := s.SetNewUserRegions(ctx, map[int64]string{1: "DE", 2: "DE", 3: "US"}); err != nil {
+		t.Fatal(err)
+	}
+	setRatings(t, s, map[int64]int{1: 1500, 2: 1700, 3: 1600, 4: 2000})
+
+	ranks, err := s.GetRegionalRanksForUsers(ctx, []int64{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]int{1: 2, 2: 1, 3: 1}
+	if len(ranks) != len(want) || ranks[1] != 2 || ranks[2] != 1 || ranks[3] != 1 {
+		t.Errorf("regional ranks = %v, want %v", ranks, want)
+	}
+
+	entries, err := s.GetRegionalLeaderboard(ctx, "DE", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].UserID != 2 || entries[1].UserID != 1 {
+		t.Errorf("DE leaderboard = %+v, want users 2 then 1", entries)
+	}
+
+	// Moving a user takes their score to the new region
+	if err := s.SetUserRegion(ctx, 1, "US"); err != nil {
+		t.Fatal(err)
+	}
+	de, _ := s.GetRegionalLeaderboard(ctx, "DE", 10, 0)
+	us, _ := s.GetRegionalLeaderboard(ctx, "US", 10, 0)
+	if len(de) != 1 || len(us) != 2 || us[1].UserID != 1 || us[1].Rating != 1500 {
+		t.Errorf("after move DE = %+v, US = %+v", de, us)
+	}
+
+	// Clearing the region drops them from regional boards only
+	if err := s.SetUserRegion(ctx, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	us, _ = s.GetRegionalLeaderboard(ctx, "US", 10, 0)
+	if len(us) != 1 {
+		t.Errorf("after clearing region US = %+v, want one entry", us)
+	}
+	if rating, ok, _ := s.GetRating(ctx, 1); !ok || rating != 1500 {
+		t.Errorf("global rating = %d, %v; want 1500", rating, ok)
+	}
+}
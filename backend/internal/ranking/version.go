@@ -0,0 +1,64 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// BoardVersionKey is incremented on every change to the leaderboard sets, so
+// any response built from them can be revalidated against it
+const BoardVersionKey = "leaderboard:version"
+
+// BoardModifiedKey holds the Unix time in milliseconds of the last change
+const BoardModifiedKey = "leaderboard:modified"
+
+// BoardVersion identifies the state of the leaderboards
+type BoardVersion struct {
+	Version  int64
+	Modified time.Time
+}
+
+// Token is an opaque string that changes whenever the leaderboards do. The
+// modification time is part of it so a reset counter never repeats a token.
+func (v BoardVersion) Token() string {
+	return fmt.Sprintf("v%d.%d", v.Version, v.Modified.UnixMilli())
+}
+
+// bump records a change as part of a transaction
+func bump(ctx context.Context, tx redis.Pipeliner) {
+	tx.Incr(ctx, BoardVersionKey)
+	tx.Set(ctx, BoardModifiedKey, time.Now().UnixMilli(), 0)
+}
+
+// Touch bumps the board version for changes made outside Redis that still
+// show up in leaderboard and profile responses, such as renames
+func (s *RankingService) Touch(ctx context.Context) error {
+	tx := s.redis.TxPipeline()
+	bump(ctx, tx)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to bump leaderboard version: %w", err)
+	}
+	return nil
+}
+
+// GetBoardVersion returns the current board version
+func (s *RankingService) GetBoardVersion(ctx context.Context) (BoardVersion, error) {
+	values, err := s.redis.MGet(ctx, BoardVersionKey, BoardModifiedKey).Result()
+	if err != nil {
+		return BoardVersion{}, fmt.Errorf("failed to get leaderboard version: %w", err)
+	}
+
+	var version BoardVersion
+	if value, ok := values[0].(string); ok {
+		version.Version, _ = strconv.ParseInt(value, 10, 64)
+	}
+	if value, ok := values[1].(string); ok {
+		millis, _ := strconv.ParseInt(value, 10, 64)
+		version.Modified = time.UnixMilli(millis)
+	}
+	return version, nil
+}
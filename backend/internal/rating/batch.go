@@ -0,0 +1,196 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"matkis-assignment/backend/internal/anticheat"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+// MaxBatchSize caps the number of updates in one batch
+const MaxBatchSize = 1000
+
+// Batch item outcomes
+const (
+	ItemUpdated  = "updated"
+	ItemHeld     = "held"
+	ItemRejected = "rejected"
+	ItemSkipped  = "skipped"
+)
+
+// BatchItem is one rating update in a batch
+type BatchItem struct {
+	UserID          int64 `json:"user_id"`
+	Rating          int   `json:"rating"`
+	ExpectedVersion int64 `json:"expected_version"`
+}
+
+// ItemResult is the outcome of one batch item
+type ItemResult struct {
+	UserID   int64    `json:"user_id"`
+	Status   string   `json:"status"`
+	Version  int64    `json:"version,omitempty"`
+	ReviewID int64    `json:"review_id,omitempty"`
+	Reasons  []string `json:"reasons,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// BatchUpdate validates every item, then applies the valid ones in one SQL
+// transaction and one Redis pipeline. Items flagged by the anti-cheat checks
+// are held for review as with Update.
+//
+// With atomic set, nothing is written (and no reviews are raised) unless every
+// item can be applied; the returned bool reports whether the batch was applied.
+func (s *Service) BatchUpdate(ctx context.Context, items []BatchItem, atomic bool) ([]ItemResult, bool, error) {
+	results := make([]ItemResult, len(items))
+	seen := make(map[int64]bool, len(items))
+	ids := make([]int64, 0, len(items))
+	for i, item := range items {
+		results[i] = ItemResult{UserID: item.UserID}
+		switch {
+		case item.UserID < 1:
+			results[i].Error = "invalid user id"
+		case item.Rating < 100 || item.Rating > 5000:
+			results[i].Error = "rating must be between 100 and 5000"
+		case item.ExpectedVersion < 0:
+			results[i].Error = "expected_version cannot be negative"
+		case seen[item.UserID]:
+			results[i].Error = "user appears more than once in the batch"
+		default:
+			seen[item.UserID] = true
+			ids = append(ids, item.UserID)
+		}
+	}
+
+	users, err := s.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, false, err
+	}
+	current := make(map[int64]*models.User, len(users))
+	for _, user := range users {
+		current[user.ID] = user
+	}
+
+	var held []int
+	for i, item := range items {
+		if results[i].Error != "" {
+			continue
+		}
+		user, ok := current[item.UserID]
+		switch {
+		case !ok:
+			results[i].Error = repository.ErrUserNotFound.Error()
+			continue
+		case user.Status == models.StatusBanned:
+			results[i].Error = ErrBanned.Error()
+			continue
+		case item.ExpectedVersion != 0 && user.Version != item.ExpectedVersion:
+			results[i].Error = repository.ErrVersionConflict.Error()
+			results[i].Version = user.Version
+			continue
+		}
+
+		reasons, err := s.antiCheat.Evaluate(ctx, anticheat.Update{
+			UserID:    item.UserID,
+			OldRating: user.Rating,
+			NewRating: item.Rating,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		if len(reasons) > 0 {
+			results[i].Reasons = reasons
+			held = append(held, i)
+		}
+	}
+
+	failed := len(held) > 0
+	for i := range results {
+		if results[i].Error != "" {
+			results[i].Status = ItemRejected
+			failed = true
+		}
+	}
+	if atomic && failed {
+		for _, i := range held {
+			results[i].Status = ItemRejected
+			results[i].Error = "flagged by the anti-cheat checks"
+		}
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = ItemSkipped
+			}
+		}
+		return results, false, nil
+	}
+
+	for _, i := range held {
+		review := &models.RatingReview{
+			UserID:      items[i].UserID,
+			OldRating:   current[items[i].UserID].Rating,
+			NewRating:   items[i].Rating,
+			UserVersion: current[items[i].UserID].Version,
+			Reasons:     results[i].Reasons,
+		}
+		if err := s.reviewRepo.Create(ctx, review); err != nil {
+			return nil, false, err
+		}
+		results[i].Status = ItemHeld
+		results[i].ReviewID = review.ID
+	}
+
+	var updates []repository.RatingUpdate
+	for i, item := range items {
+		if results[i].Status == "" {
+			updates = append(updates, repository.RatingUpdate{
+				UserID:          item.UserID,
+				Rating:          item.Rating,
+				ExpectedVersion: item.ExpectedVersion,
+			})
+		}
+	}
+
+	updated, err := s.userRepo.UpdateRatings(ctx, updates, atomic)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		// Another writer got in between the check and the update
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = ItemSkipped
+			}
+		}
+		return results, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	writes := make([]ranking.RatingWrite, 0, len(updated))
+	for i, item := range items {
+		if results[i].Status != "" {
+			continue
+		}
+		user, ok := updated[item.UserID]
+		if !ok {
+			results[i].Status = ItemRejected
+			results[i].Error = repository.ErrVersionConflict.Error()
+			continue
+		}
+		results[i].Status = ItemUpdated
+		results[i].Version = user.Version
+		writes = append(writes, ranking.RatingWrite{UserID: user.ID, Rating: user.Rating, Version: user.Version})
+	}
+
+	if err := s.rankService.UpdateUserRatings(ctx, writes); err != nil {
+		return nil, false, fmt.Errorf("failed to update leaderboard: %w", err)
+	}
+	for _, user := range updated {
+		if err := s.promoteIfEstablished(ctx, user); err != nil {
+			return nil, false, err
+		}
+	}
+	return results, true, nil
+}
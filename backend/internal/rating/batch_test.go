@@ -0,0 +1,117 @@
+package rating
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"matkis-assignment/backend/internal/anticheat"
+)
+
+func TestBatchUpdatePerItem(t *testing.T) {
+	s, rankService, mock := newTestService(t, 0, anticheat.MaxDelta{Limit: 300})
+	ctx := context.Background()
+
+	mock.ExpectQuery("FROM users").WillReturnRows(userRows(testUser(1, 1500, 3), testUser(2, 1500, 5), testUser(4, 1500, 1)))
+	mock.ExpectQuery("INSERT INTO rating_reviews").WithArgs(4, 1500, 2500, 1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(9, "pending", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE users\s+SET rating = v.new_rating`).WithArgs(1, 1600, 3).
+		WillReturnRows(userRows(testUser(1, 1600, 4)))
+	mock.ExpectCommit()
+
+	results, applied, err := s.BatchUpdate(ctx, []BatchItem{
+		{UserID: 1, Rating: 1600, ExpectedVersion: 3},
+		{UserID: 2, Rating: 1600, ExpectedVersion: 4},
+		{UserID: 3, Rating: 50},
+		{UserID: 4, Rating: 2500},
+		{UserID: 1, Rating: 1700},
+		{UserID: 5, Rating: 1600},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applied {
+		t.Fatal("batch was not applied")
+	}
+
+	want := []string{ItemUpdated, ItemRejected, ItemRejected, ItemHeld, ItemRejected, ItemRejected}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("item %d status = %s (%s), want %s", i, result.Status, result.Error, want[i])
+		}
+	}
+	if results[0].Version != 4 {
+		t.Errorf("updated version = %d, want 4", results[0].Version)
+	}
+	if results[1].Version != 5 {
+		t.Errorf("conflict reports version %d, want the current 5", results[1].Version)
+	}
+	if results[3].ReviewID != 9 {
+		t.Errorf("held review id = %d, want 9", results[3].ReviewID)
+	}
+	if rating, ok, _ := rankService.GetRating(ctx, 1); !ok || rating != 1600 {
+		t.Errorf("leaderboard rating = %d, %v; want 1600", rating, ok)
+	}
+	if _, ok, _ := rankService.GetRating(ctx, 4); ok {
+		t.Error("held user was written to the leaderboard")
+	}
+}
+
+func TestBatchUpdateAtomic(t *testing.T) {
+	t.Run("invalid item", func(t *testing.T) {
+		s, rankService, mock := newTestService(t, 0)
+		ctx := context.Background()
+
+		mock.ExpectQuery("FROM users").WillReturnRows(userRows(testUser(1, 1500, 3)))
+
+		results, applied, err := s.BatchUpdate(ctx, []BatchItem{
+			{UserID: 1, Rating: 1600},
+			{UserID: 2, Rating: 9000},
+		}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied {
+			t.Error("batch with an invalid item was applied")
+		}
+		if results[0].Status != ItemSkipped || results[1].Status != ItemRejected {
+			t.Errorf("statuses = %s, %s; want skipped, rejected", results[0].Status, results[1].Status)
+		}
+		if _, ok, _ := rankService.GetRating(ctx, 1); ok {
+			t.Error("skipped item was written to the leaderboard")
+		}
+	})
+
+	t.Run("concurrent write", func(t *testing.T) {
+		s, rankService, mock := newTestService(t, 0)
+		ctx := context.Background()
+
+		mock.ExpectQuery("FROM users").WillReturnRows(userRows(testUser(1, 1500, 3), testUser(2, 1500, 3)))
+		mock.ExpectBegin()
+		// User 2 moved on between the read and the update
+		mock.ExpectQuery(`UPDATE users\s+SET rating = v.new_rating`).
+			WillReturnRows(userRows(testUser(1, 1600, 4)))
+		mock.ExpectRollback()
+
+		results, applied, err := s.BatchUpdate(ctx, []BatchItem{
+			{UserID: 1, Rating: 1600, ExpectedVersion: 3},
+			{UserID: 2, Rating: 1600, ExpectedVersion: 3},
+		}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied {
+			t.Error("batch was applied despite a version conflict")
+		}
+		for i, result := range results {
+			if result.Status != ItemSkipped {
+				t.Errorf("item %d status = %s, want skipped", i, result.Status)
+			}
+		}
+		if _, ok, _ := rankService.GetRating(ctx, 1); ok {
+			t.Error("rolled back update was written to the leaderboard")
+		}
+	})
+}
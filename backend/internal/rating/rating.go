@@ -0,0 +1,144 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"matkis-assignment/backend/internal/anticheat"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+var ErrBanned = errors.New("user is banned")
+
+// Result is the outcome of a rating update: either the updated user, or the
+// review the update is held in
+type Result struct {
+	User   *models.User
+	Review *models.RatingReview
+}
+
+// Service is the write path for rating updates, keeping PostgreSQL and the
+// Redis leaderboards in step
+type Service struct {
+	userRepo    *repository.UserRepository
+	reviewRepo  *repository.ReviewRepository
+	rankService *ranking.RankingService
+	antiCheat   *anticheat.Service
+
+	// provisionalGames is the number of rated games a new user plays before
+	// joining the public leaderboard; 0 disables the provisional state
+	provisionalGames int
+}
+
+func NewService(userRepo *repository.UserRepository, reviewRepo *repository.ReviewRepository, rankService *ranking.RankingService, antiCheat *anticheat.Service, provisionalGames int) *Service {
+	return &Service{
+		userRepo:         userRepo,
+		reviewRepo:       reviewRepo,
+		rankService:      rankService,
+		antiCheat:        antiCheat,
+		provisionalGames: provisionalGames,
+	}
+}
+
+// Provisional reports whether new users start out provisional
+func (s *Service) Provisional() bool {
+	return s.provisionalGames > 0
+}
+
+// Update runs the anti-cheat checks and applies the rating, or holds it for
+// review if any check flags it. A non-zero expectedVersion must match the
+// user's current version or repository.ErrVersionConflict is returned.
+func (s *Service) Update(ctx context.Context, userID int64, rating int, expectedVersion int64) (*Result, error) {
+	current, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == models.StatusBanned {
+		return nil, ErrBanned
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return nil, repository.ErrVersionConflict
+	}
+
+	reasons, err := s.antiCheat.Evaluate(ctx, anticheat.Update{
+		UserID:    userID,
+		OldRating: current.Rating,
+		NewRating: rating,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(reasons) > 0 {
+		review := &models.RatingReview{
+			UserID:      userID,
+			OldRating:   current.Rating,
+			NewRating:   rating,
+			UserVersion: current.Version,
+			Reasons:     reasons,
+		}
+		if err := s.reviewRepo.Create(ctx, review); err != nil {
+			return nil, err
+		}
+		return &Result{Review: review}, nil
+	}
+
+	user, err := s.Apply(ctx, userID, rating, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{User: user}, nil
+}
+
+// ApplyReview writes the rating held in an approved review. It returns
+// ErrBanned if the user has been banned since, and
+// repository.ErrVersionConflict if their rating has changed since.
+func (s *Service) ApplyReview(ctx context.Context, review *models.RatingReview) (*models.User, error) {
+	current, err := s.userRepo.GetByID(ctx, review.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == models.StatusBanned {
+		return nil, ErrBanned
+	}
+	return s.Apply(ctx, review.UserID, review.NewRating, review.UserVersion)
+}
+
+// Apply writes a rating without any checks, promoting provisional users once
+// they have played enough rated games. expectedVersion is as for Update.
+func (s *Service) Apply(ctx context.Context, userID int64, rating int, expectedVersion int64) (*models.User, error) {
+	// Update in PostgreSQL; the conditional update settles races between writers
+	user, err := s.userRepo.UpdateRating(ctx, userID, rating, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update in Redis, dropping the write if a newer version is already there
+	if err := s.rankService.UpdateUserRating(ctx, userID, rating, user.Version); err != nil {
+		return nil, fmt.Errorf("failed to update leaderboard: %w", err)
+	}
+
+	if err := s.promoteIfEstablished(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// promoteIfEstablished moves a provisional user onto the public leaderboard
+// once they have played enough rated games. Redis goes first so a failed
+// promotion is retried on the next update.
+func (s *Service) promoteIfEstablished(ctx context.Context, user *models.User) error {
+	if !user.Provisional || user.GamesPlayed < s.provisionalGames {
+		return nil
+	}
+	if err := s.rankService.Promote(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to update leaderboard: %w", err)
+	}
+	if err := s.userRepo.MarkEstablished(ctx, user.ID); err != nil {
+		return err
+	}
+	user.Provisional = false
+	return nil
+}
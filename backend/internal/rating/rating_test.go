@@ -0,0 +1,179 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/anticheat"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func newTestService(t *testing.T, provisionalGames int, checks ...anticheat.Check) (*Service, *ranking.RankingService, sqlmock.Sqlmock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	rankService := ranking.NewRankingService(client)
+	s := NewService(repository.NewUserRepository(db), repository.NewReviewRepository(db), rankService,
+		anticheat.NewService(checks...), provisionalGames)
+	return s, rankService, mock
+}
+
+// userRows builds the rows a user query returns, in userColumns order
+func userRows(users ...*models.User) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "rating", "region", "games_played", "provisional", "status", "version", "created_at", "updated_at",
+	})
+	for _, user := range users {
+		rows.AddRow(user.ID, user.Username, user.Rating, user.Region, user.GamesPlayed, user.Provisional,
+			user.Status, user.Version, user.CreatedAt, user.UpdatedAt)
+	}
+	return rows
+}
+
+func testUser(id int64, rating int, version int64) *models.User {
+	return &models.User{ID: id, Username: "player", Rating: rating, Status: models.StatusActive, Version: version, GamesPlayed: 10}
+}
+
+func TestUpdateApplies(t *testing.T) {
+	s, rankService, mock := newTestService(t, 0, anticheat.MaxDelta{Limit: 300})
+
+	mock.ExpectQuery("FROM users WHERE id").WithArgs(1).WillReturnRows(userRows(testUser(1, 1500, 3)))
+	mock.ExpectQuery("UPDATE users SET rating").WithArgs(1600, 1, 0).WillReturnRows(userRows(testUser(1, 1600, 4)))
+
+	result, err := s.Update(context.Background(), 1, 1600, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Review != nil || result.User == nil || result.User.Version != 4 {
+		t.Fatalf("result = %+v, want the updated user at version 4", result)
+	}
+	if rating, ok, _ := rankService.GetRating(context.Background(), 1); !ok || rating != 1600 {
+		t.Errorf("leaderboard rating = %d, %v; want 1600", rating, ok)
+	}
+}
+
+func TestUpdateHeldForReview(t *testing.T) {
+	s, rankService, mock := newTestService(t, 0, anticheat.MaxDelta{Limit: 300})
+
+	mock.ExpectQuery("FROM users WHERE id").WithArgs(1).WillReturnRows(userRows(testUser(1, 1500, 3)))
+	mock.ExpectQuery("INSERT INTO rating_reviews").WithArgs(1, 1500, 2500, 3, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(7, "pending", time.Now()))
+
+	result, err := s.Update(context.Background(), 1, 2500, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.User != nil || result.Review == nil || result.Review.ID != 7 || len(result.Review.Reasons) != 1 {
+		t.Fatalf("result = %+v, want review 7 with one reason", result)
+	}
+	if _, ok, _ := rankService.GetRating(context.Background(), 1); ok {
+		t.Error("a held update reached the leaderboard")
+	}
+}
+
+func TestUpdateRejectsBanned(t *testing.T) {
+	s, _, mock := newTestService(t, 0)
+
+	banned := testUser(1, 1500, 3)
+	banned.Status = models.StatusBanned
+	mock.ExpectQuery("FROM users WHERE id").WithArgs(1).WillReturnRows(userRows(banned))
+
+	if _, err := s.Update(context.Background(), 1, 1600, 0); !errors.Is(err, ErrBanned) {
+		t.Errorf("Update for a banned user = %v, want ErrBanned", err)
+	}
+}
+
+func TestApplyPromotesEstablishedUsers(t *testing.T) {
+	s, rankService, mock := newTestService(t, 5)
+	ctx := context.Background()
+	if err := rankService.AddProvisional(ctx, 1, 1500); err != nil {
+		t.Fatal(err)
+	}
+
+	user := testUser(1, 1550, 5)
+	user.Provisional, user.GamesPlayed = true, 4
+	mock.ExpectQuery("UPDATE users SET rating").WillReturnRows(userRows(user))
+	if _, err := s.Apply(ctx, 1, 1550, 0); err != nil {
+		t.Fatal(err)
+	}
+	if ranks, _ := rankService.GetRanksForUsers(ctx, []int64{1}); len(ranks) != 0 {
+		t.Fatal("promoted before playing enough games")
+	}
+
+	user = testUser(1, 1600, 6)
+	user.Provisional, user.GamesPlayed = true, 5
+	mock.ExpectQuery("UPDATE users SET rating").WillReturnRows(userRows(user))
+	mock.ExpectExec("UPDATE users SET provisional = FALSE").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	updated, err := s.Apply(ctx, 1, 1600, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Provisional {
+		t.Error("user is still marked provisional")
+	}
+	if ranks, _ := rankService.GetRanksForUsers(ctx, []int64{1}); ranks[1] != 1 {
+		t.Errorf("ranks after promotion = %v, want user 1 ranked 1", ranks)
+	}
+}
+
+func TestApplyReview(t *testing.T) {
+	review := &models.RatingReview{ID: 7, UserID: 1, OldRating: 1500, NewRating: 2500, UserVersion: 3}
+
+	t.Run("applied", func(t *testing.T) {
+		s, rankService, mock := newTestService(t, 0)
+		mock.ExpectQuery("FROM users WHERE id").WithArgs(1).WillReturnRows(userRows(testUser(1, 1500, 3)))
+		mock.ExpectQuery("UPDATE users SET rating").WithArgs(2500, 1, 3).WillReturnRows(userRows(testUser(1, 2500, 4)))
+
+		if _, err := s.ApplyReview(context.Background(), review); err != nil {
+			t.Fatal(err)
+		}
+		if rating, ok, _ := rankService.GetRating(context.Background(), 1); !ok || rating != 2500 {
+			t.Errorf("leaderboard rating = %d, %v; want 2500", rating, ok)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		s, rankService, mock := newTestService(t, 0)
+		mock.ExpectQuery("FROM users WHERE id").WithArgs(1).WillReturnRows(userRows(testUser(1, 1700, 5)))
+		mock.ExpectQuery("UPDATE users SET rating").WithArgs(2500, 1, 3).WillReturnRows(userRows())
+		mock.ExpectQuery("FROM users WHERE id").WithArgs(1).WillReturnRows(userRows(testUser(1, 1700, 5)))
+
+		if _, err := s.ApplyReview(context.Background(), review); !errors.Is(err, repository.ErrVersionConflict) {
+			t.Errorf("err = %v, want ErrVersionConflict", err)
+		}
+		if _, ok, _ := rankService.GetRating(context.Background(), 1); ok {
+			t.Error("a stale review reached the leaderboard")
+		}
+	})
+
+	t.Run("banned since", func(t *testing.T) {
+		s, _, mock := newTestService(t, 0)
+		banned := testUser(1, 1500, 3)
+		banned.Status = models.StatusBanned
+		mock.ExpectQuery("FROM users WHERE id").WithArgs(1).WillReturnRows(userRows(banned))
+
+		if _, err := s.ApplyReview(context.Background(), review); !errors.Is(err, ErrBanned) {
+			t.Errorf("err = %v, want ErrBanned", err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// MaxFriends caps how many friends a user can follow so friend leaderboards
+// stay a single ZMSCORE call
+const MaxFriends = 1000
+
+var ErrTooManyFriends = errors.New("friend limit reached")
+
+type FriendRepository struct {
+	db *sql.DB
+}
+
+func NewFriendRepository(db *sql.DB) *FriendRepository {
+	return &FriendRepository{db: db}
+}
+
+// Add makes userID follow friendID. Adding an existing friend is a no-op.
+func (r *FriendRepository) Add(ctx context.Context, userID, friendID int64) error {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM friendships WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count friends: %w", err)
+	}
+	if count >= MaxFriends {
+		return ErrTooManyFriends
+	}
+
+	query := `
+		INSERT INTO friendships (user_id, friend_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID, friendID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			// foreign_key_violation: one of the users does not exist
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to add friend: %w", err)
+	}
+	return nil
+}
+
+func (r *FriendRepository) Remove(ctx context.Context, userID, friendID int64) error {
+	query := `DELETE FROM friendships WHERE user_id = $1 AND friend_id = $2`
+	result, err := r.db.ExecContext(ctx, query, userID, friendID)
+	if err != nil {
+		return fmt.Errorf("failed to remove friend: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ListFriendIDs returns the IDs of the users userID follows
+func (r *FriendRepository) ListFriendIDs(ctx context.Context, userID int64) ([]int64, error) {
+	query := `
+		SELECT friend_id
+		FROM friendships
+		WHERE user_id = $1
+		ORDER BY created_at
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, MaxFriends)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list friends: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan friend: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"matkis-assignment/backend/internal/models"
+)
+
+type HistoryRepository struct {
+	db *sql.DB
+}
+
+func NewHistoryRepository(db *sql.DB) *HistoryRepository {
+	return &HistoryRepository{db: db}
+}
+
+// LogChange appends a rating change. A nil oldRating means the user was not
+// on the leaderboard before; a nil newRating means they were removed from it.
+func (r *HistoryRepository) LogChange(ctx context.Context, userID int64, oldRating, newRating *int) error {
+	query := `INSERT INTO rating_changes (user_id, old_rating, new_rating) VALUES ($1, $2, $3)`
+	if _, err := r.db.ExecContext(ctx, query, userID, oldRating, newRating); err != nil {
+		return fmt.Errorf("failed to log rating change: %w", err)
+	}
+	return nil
+}
+
+// ForEachChange calls fn for every change in (from, to] in the order they were logged
+func (r *HistoryRepository) ForEachChange(ctx context.Context, from, to time.Time, fn func(models.RatingChange)) error {
+	query := `
+		SELECT id, user_id, old_rating, new_rating, changed_at
+		FROM rating_changes
+		WHERE changed_at > $1 AND changed_at <= $2
+		ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get rating changes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		change, err := scanRatingChange(rows)
+		if err != nil {
+			return err
+		}
+		fn(change)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	return nil
+}
+
+// ListChanges returns every logged rating change for a user, oldest first
+func (r *HistoryRepository) ListChanges(ctx context.Context, userID int64) ([]models.RatingChange, error) {
+	query := `
+		SELECT id, user_id, old_rating, new_rating, changed_at
+		FROM rating_changes
+		WHERE user_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.RatingChange{}
+	for rows.Next() {
+		change, err := scanRatingChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return changes, nil
+}
+
+// RecentDeltas returns the user's most recent rating changes as new minus old
+// rating, newest first. Changes onto or off the leaderboard are skipped.
+func (r *HistoryRepository) RecentDeltas(ctx context.Context, userID int64, limit int) ([]int, error) {
+	query := `
+		SELECT new_rating - old_rating
+		FROM rating_changes
+		WHERE user_id = $1 AND old_rating IS NOT NULL AND new_rating IS NOT NULL
+		ORDER BY id DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating deltas: %w", err)
+	}
+	defer rows.Close()
+
+	var deltas []int
+	for rows.Next() {
+		var delta int
+		if err := rows.Scan(&delta); err != nil {
+			return nil, fmt.Errorf("failed to scan rating delta: %w", err)
+		}
+		deltas = append(deltas, delta)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return deltas, nil
+}
+
+func scanRatingChange(row scanner) (models.RatingChange, error) {
+	var change models.RatingChange
+	var oldRating, newRating sql.NullInt64
+	if err := row.Scan(&change.ID, &change.UserID, &oldRating, &newRating, &change.ChangedAt); err != nil {
+		return change, fmt.Errorf("failed to scan rating change: %w", err)
+	}
+	if oldRating.Valid {
+		v := int(oldRating.Int64)
+		change.OldRating = &v
+	}
+	if newRating.Valid {
+		v := int(newRating.Int64)
+		change.NewRating = &v
+	}
+	return change, nil
+}
+
+// Now returns the database clock, which rating_changes timestamps are taken from
+func (r *HistoryRepository) Now(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	if err := r.db.QueryRowContext(ctx, `SELECT NOW()::timestamp`).Scan(&now); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get database time: %w", err)
+	}
+	return now, nil
+}
+
+func (r *HistoryRepository) SaveSnapshot(ctx context.Context, takenAt time.Time, userCount int, data []byte) error {
+	query := `INSERT INTO leaderboard_snapshots (taken_at, user_count, data) VALUES ($1, $2, $3)`
+	if _, err := r.db.ExecContext(ctx, query, takenAt, userCount, data); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// ExistingUsers returns which of ids still have a users row
+func (r *HistoryRepository) ExistingUsers(ctx context.Context, ids []int64) (map[int64]bool, error) {
+	existing := make(map[int64]bool, len(ids))
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM users WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		existing[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return existing, nil
+}
+
+// LatestSnapshotAt returns the ID and time of the newest snapshot taken at or
+// before t. ok is false when no such snapshot exists.
+func (r *HistoryRepository) LatestSnapshotAt(ctx context.Context, t time.Time) (int64, time.Time, bool, error) {
+	var id int64
+	var takenAt time.Time
+	query := `
+		SELECT id, taken_at
+		FROM leaderboard_snapshots
+		WHERE taken_at <= $1
+		ORDER BY taken_at DESC
+		LIMIT 1
+	`
+	err := r.db.QueryRowContext(ctx, query, t).Scan(&id, &takenAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, time.Time{}, false, nil
+		}
+		return 0, time.Time{}, false, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return id, takenAt, true, nil
+}
+
+// SnapshotData returns the encoded ratings of a snapshot
+func (r *HistoryRepository) SnapshotData(ctx context.Context, id int64) ([]byte, error) {
+	var data []byte
+	err := r.db.QueryRowContext(ctx, `SELECT data FROM leaderboard_snapshots WHERE id = $1`, id).Scan(&data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot data: %w", err)
+	}
+	return data, nil
+}
+
+// SaveRankSnapshots upserts one day's ranks for a batch of users
+func (r *HistoryRepository) SaveRankSnapshots(ctx context.Context, date time.Time, userIDs []int64, ranks, ratings []int) error {
+	query := `
+		INSERT INTO rank_snapshots (user_id, snapshot_date, rank, rating)
+		SELECT u.user_id, $1, u.rank, u.rating
+		FROM unnest($2::bigint[], $3::int[], $4::int[]) AS u(user_id, rank, rating)
+		JOIN users ON users.id = u.user_id
+		ON CONFLICT (user_id, snapshot_date) DO UPDATE
+		SET rank = EXCLUDED.rank, rating = EXCLUDED.rating
+	`
+	_, err := r.db.ExecContext(ctx, query, date.Format("2006-01-02"),
+		pq.Array(userIDs), pq.Array(ranks), pq.Array(ratings))
+	if err != nil {
+		return fmt.Errorf("failed to save rank snapshots: %w", err)
+	}
+	return nil
+}
+
+// GetRankHistory returns a user's daily ranks since the given date, oldest first
+func (r *HistoryRepository) GetRankHistory(ctx context.Context, userID int64, since time.Time) ([]models.RankSnapshot, error) {
+	query := `
+		SELECT to_char(snapshot_date, 'YYYY-MM-DD'), rank, rating
+		FROM rank_snapshots
+		WHERE user_id = $1 AND snapshot_date >= $2
+		ORDER BY snapshot_date
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rank history: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []models.RankSnapshot{}
+	for rows.Next() {
+		var snapshot models.RankSnapshot
+		if err := rows.Scan(&snapshot.Date, &snapshot.Rank, &snapshot.Rating); err != nil {
+			return nil, fmt.Errorf("failed to scan rank snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return snapshots, nil
+}
+
+// GetMovers compares the latest rank snapshot with the one `days` earlier.
+// climbers is true for the biggest rank gains, false for the biggest drops.
+func (r *HistoryRepository) GetMovers(ctx context.Context, days int, climbers bool, limit int) ([]models.RankMover, error) {
+	order := "ASC"
+	filter := "prev.rank < cur.rank"
+	if climbers {
+		order = "DESC"
+		filter = "prev.rank > cur.rank"
+	}
+
+	query := `
+		WITH latest AS (SELECT MAX(snapshot_date) AS d FROM rank_snapshots)
+		SELECT cur.user_id, users.username, prev.rank, cur.rank, prev.rank - cur.rank AS change, cur.rating
+		FROM latest
+		JOIN rank_snapshots cur ON cur.snapshot_date = latest.d
+		JOIN rank_snapshots prev ON prev.user_id = cur.user_id AND prev.snapshot_date = latest.d - $1::int
+		JOIN users ON users.id = cur.user_id
+		WHERE ` + filter + `
+		ORDER BY change ` + order + `, cur.rank
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, days, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movers: %w", err)
+	}
+	defer rows.Close()
+
+	movers := []models.RankMover{}
+	for rows.Next() {
+		var mover models.RankMover
+		if err := rows.Scan(
+			&mover.UserID, &mover.Username, &mover.PreviousRank, &mover.Rank, &mover.Change, &mover.Rating,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan mover: %w", err)
+		}
+		movers = append(movers, mover)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return movers, nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"matkis-assignment/backend/internal/models"
+)
+
+type MatchRepository struct {
+	db *sql.DB
+}
+
+func NewMatchRepository(db *sql.DB) *MatchRepository {
+	return &MatchRepository{db: db}
+}
+
+func (r *MatchRepository) Create(ctx context.Context, match *models.Match) error {
+	query := `
+		INSERT INTO matches (player_a, player_b, winner_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, played_at
+	`
+	err := r.db.QueryRowContext(ctx, query, match.PlayerA, match.PlayerB, match.WinnerID).Scan(
+		&match.ID, &match.PlayedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to create match: %w", err)
+	}
+	return nil
+}
+
+// RecentOpponents returns everyone the user has played since the given time
+func (r *MatchRepository) RecentOpponents(ctx context.Context, userID int64, since time.Time) ([]int64, error) {
+	query := `
+		SELECT DISTINCT CASE WHEN player_a = $1 THEN player_b ELSE player_a END
+		FROM matches
+		WHERE (player_a = $1 OR player_b = $1) AND played_at >= $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent opponents: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan opponent: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return ids, nil
+}
+
+// HeadToHead summarises all matches between a and b from a's point of view
+func (r *MatchRepository) HeadToHead(ctx context.Context, a, b int64) (*models.HeadToHead, error) {
+	record := &models.HeadToHead{}
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE winner_id = $1),
+			COUNT(*) FILTER (WHERE winner_id = $2),
+			COUNT(*) FILTER (WHERE winner_id IS NULL)
+		FROM matches
+		WHERE (player_a = $1 AND player_b = $2) OR (player_a = $2 AND player_b = $1)
+	`
+	err := r.db.QueryRowContext(ctx, query, a, b).Scan(
+		&record.Matches, &record.AWins, &record.BWins, &record.Draws,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get head-to-head record: %w", err)
+	}
+	return record, nil
+}
+
+// ListForUser returns every match the user played, newest first
+func (r *MatchRepository) ListForUser(ctx context.Context, userID int64) ([]*models.Match, error) {
+	query := `
+		SELECT id, player_a, player_b, winner_id, played_at
+		FROM matches
+		WHERE player_a = $1 OR player_b = $1
+		ORDER BY played_at DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list matches: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []*models.Match{}
+	for rows.Next() {
+		match := &models.Match{}
+		var winnerID sql.NullInt64
+		if err := rows.Scan(&match.ID, &match.PlayerA, &match.PlayerB, &winnerID, &match.PlayedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan match: %w", err)
+		}
+		if winnerID.Valid {
+			match.WinnerID = &winnerID.Int64
+		}
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return matches, nil
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+// rankedUsers matches the users the Redis leaderboards hold
+const rankedUsers = "NOT provisional AND status = 'active'"
+
+// RankingFallback computes leaderboard pages and ranks in PostgreSQL, for use
+// while Redis is unavailable. It is much slower than the sorted sets but
+// gives the same tie-aware ranks.
+type RankingFallback struct {
+	db *sql.DB
+}
+
+func NewRankingFallback(db *sql.DB) *RankingFallback {
+	return &RankingFallback{db: db}
+}
+
+// LeaderboardPage ranks with RANK() OVER the rating order, which the planner
+// can feed from idx_users_rating (or idx_users_region_rating). The rating
+// range and the page are applied to the ranked rows, so ranks count every
+// ranked user and not just those in the range.
+func (f *RankingFallback) LeaderboardPage(ctx context.Context, region string, minRating, maxRating, limit, offset int) ([]ranking.LeaderboardEntry, error) {
+	filter := rankedUsers
+	args := []interface{}{minRating, maxRating, limit, offset}
+	if region != "" {
+		filter += " AND region = $5"
+		args = append(args, region)
+	}
+
+	query := `
+		SELECT id, rating, rank
+		FROM (
+			SELECT id, rating, RANK() OVER (ORDER BY rating DESC) AS rank
+			FROM users
+			WHERE ` + filter + `
+		) ranked
+		WHERE rating BETWEEN $1 AND $2
+		ORDER BY rating DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := f.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard page: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []ranking.LeaderboardEntry{}
+	for rows.Next() {
+		var entry ranking.LeaderboardEntry
+		if err := rows.Scan(&entry.UserID, &entry.Rating, &entry.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return entries, nil
+}
+
+// RanksForUsers ranks every ranked user with RANK(), per region if regional,
+// and keeps the requested ones
+func (f *RankingFallback) RanksForUsers(ctx context.Context, userIDs []int64, regional bool) (map[int64]int, error) {
+	ranks := make(map[int64]int)
+	if len(userIDs) == 0 {
+		return ranks, nil
+	}
+
+	window, kept := "", ""
+	if regional {
+		window, kept = "PARTITION BY region ", " AND region <> ''"
+	}
+	query := `
+		SELECT id, rank
+		FROM (
+			SELECT id, region, RANK() OVER (` + window + `ORDER BY rating DESC) AS rank
+			FROM users
+			WHERE ` + rankedUsers + `
+		) ranked
+		WHERE id = ANY($1)` + kept
+
+	rows, err := f.db.QueryContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ranks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		var rank int
+		if err := rows.Scan(&userID, &rank); err != nil {
+			return nil, fmt.Errorf("failed to scan rank: %w", err)
+		}
+		ranks[userID] = rank
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return ranks, nil
+}
+
+func (f *RankingFallback) CountRatings(ctx context.Context, rating int) (int, int, int, error) {
+	var higher, lower, total int
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE rating > $1),
+			COUNT(*) FILTER (WHERE rating < $1),
+			COUNT(*)
+		FROM users
+		WHERE ` + rankedUsers
+	if err := f.db.QueryRowContext(ctx, query, rating).Scan(&higher, &lower, &total); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to count ratings: %w", err)
+	}
+	return higher, lower, total, nil
+}
+
+func (f *RankingFallback) CountInRanges(ctx context.Context, ranges [][2]int) ([]int, error) {
+	counts := make([]int, len(ranges))
+	if len(ranges) == 0 {
+		return counts, nil
+	}
+
+	filters := make([]string, len(ranges))
+	args := make([]interface{}, 0, len(ranges)*2)
+	dest := make([]interface{}, len(ranges))
+	for i, r := range ranges {
+		filters[i] = fmt.Sprintf("COUNT(*) FILTER (WHERE rating BETWEEN $%d AND $%d)", i*2+1, i*2+2)
+		args = append(args, r[0], r[1])
+		dest[i] = &counts[i]
+	}
+	query := `SELECT ` + strings.Join(filters, ", ") + ` FROM users WHERE ` + rankedUsers
+	if err := f.db.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to count rating ranges: %w", err)
+	}
+	return counts, nil
+}
+
+func (f *RankingFallback) RatingAtPosition(ctx context.Context, position int) (int, bool, error) {
+	var rating int
+	query := `SELECT rating FROM users WHERE ` + rankedUsers + ` ORDER BY rating DESC LIMIT 1 OFFSET $1`
+	err := f.db.QueryRowContext(ctx, query, position).Scan(&rating)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get rating at position: %w", err)
+	}
+	return rating, true, nil
+}
+
+func (f *RankingFallback) Ratings(ctx context.Context, userIDs []int64) (map[int64]int, error) {
+	ratings := make(map[int64]int)
+	if len(userIDs) == 0 {
+		return ratings, nil
+	}
+
+	query := `SELECT id, rating FROM users WHERE id = ANY($1) AND ` + rankedUsers
+	rows, err := f.db.QueryContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ratings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		var rating int
+		if err := rows.Scan(&userID, &rating); err != nil {
+			return nil, fmt.Errorf("failed to scan rating: %w", err)
+		}
+		ratings[userID] = rating
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return ratings, nil
+}
+
+// ShadowRank ranks a moderated user with RANK() among the ranked users, the
+// same way the moderated set is ranked in Redis. Being the only unranked row
+// in the window, the user is not counted against anyone but themselves.
+func (f *RankingFallback) ShadowRank(ctx context.Context, userID int64, regional bool) (int, bool, error) {
+	window, kept := "", ""
+	if regional {
+		window, kept = "PARTITION BY region ", " AND region <> ''"
+	}
+	query := `
+		SELECT rank
+		FROM (
+			SELECT id, region, status, RANK() OVER (` + window + `ORDER BY rating DESC) AS rank
+			FROM users
+			WHERE (` + rankedUsers + `) OR id = $1
+		) ranked
+		WHERE id = $1 AND status <> 'active'` + kept
+
+	var rank int
+	err := f.db.QueryRowContext(ctx, query, userID).Scan(&rank)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get shadow rank: %w", err)
+	}
+	return rank, true, nil
+}
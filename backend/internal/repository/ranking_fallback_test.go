@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newTestFallback(t *testing.T) (*RankingFallback, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return NewRankingFallback(db), mock
+}
+
+func TestFallbackLeaderboardPage(t *testing.T) {
+	f, mock := newTestFallback(t)
+
+	// Ranks are taken over every ranked user before the range and page apply
+	mock.ExpectQuery(`RANK\(\) OVER \(ORDER BY rating DESC\) AS rank\s+FROM users\s+WHERE NOT provisional AND status = 'active' AND region = \$5\s+\) ranked\s+WHERE rating BETWEEN \$1 AND \$2`).
+		WithArgs(1000, 2000, 2, 10, "DE").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rating", "rank"}).AddRow(5, 1800, 9).AddRow(6, 1800, 9))
+
+	entries, err := f.LeaderboardPage(context.Background(), "DE", 1000, 2000, 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Rank != 9 || entries[1].UserID != 6 {
+		t.Errorf("entries = %+v, want users 5 and 6 tied at rank 9", entries)
+	}
+}
+
+func TestFallbackRanksForUsers(t *testing.T) {
+	f, mock := newTestFallback(t)
+
+	mock.ExpectQuery(`RANK\(\) OVER \(ORDER BY rating DESC\)[\s\S]+\) ranked\s+WHERE id = ANY\(\$1\)$`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rank"}).AddRow(1, 4).AddRow(2, 9))
+	mock.ExpectQuery(`RANK\(\) OVER \(PARTITION BY region ORDER BY rating DESC\)[\s\S]+WHERE id = ANY\(\$1\) AND region <> ''`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rank"}).AddRow(1, 2))
+
+	ranks, err := f.RanksForUsers(context.Background(), []int64{1, 2, 3}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranks) != 2 || ranks[1] != 4 || ranks[2] != 9 {
+		t.Errorf("ranks = %v, want users 1 and 2 at 4 and 9", ranks)
+	}
+	ranks, err = f.RanksForUsers(context.Background(), []int64{1, 3}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranks) != 1 || ranks[1] != 2 {
+		t.Errorf("regional ranks = %v, want user 1 at 2", ranks)
+	}
+}
+
+func TestFallbackCountInRanges(t *testing.T) {
+	f, mock := newTestFallback(t)
+
+	mock.ExpectQuery(`FILTER \(WHERE rating BETWEEN \$1 AND \$2\), COUNT\(\*\) FILTER \(WHERE rating BETWEEN \$3 AND \$4\)`).
+		WithArgs(100, 999, 1000, 5000).
+		WillReturnRows(sqlmock.NewRows([]string{"a", "b"}).AddRow(4, 7))
+
+	counts, err := f.CountInRanges(context.Background(), [][2]int{{100, 999}, {1000, 5000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[0] != 4 || counts[1] != 7 {
+		t.Errorf("counts = %v, want [4 7]", counts)
+	}
+}
+
+func TestFallbackShadowRank(t *testing.T) {
+	f, mock := newTestFallback(t)
+
+	mock.ExpectQuery("status <> 'active'").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}).AddRow(12))
+	mock.ExpectQuery("status <> 'active'").WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}))
+
+	if rank, ok, err := f.ShadowRank(context.Background(), 3, false); err != nil || !ok || rank != 12 {
+		t.Errorf("ShadowRank(3) = %d, %v, %v; want 12, true", rank, ok, err)
+	}
+	if _, ok, err := f.ShadowRank(context.Background(), 4, false); err != nil || ok {
+		t.Errorf("ShadowRank(4) = %v, %v; want not moderated", ok, err)
+	}
+
+	mock.ExpectQuery("PARTITION BY region").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}).AddRow(2))
+	if rank, ok, err := f.ShadowRank(context.Background(), 3, true); err != nil || !ok || rank != 2 {
+		t.Errorf("regional ShadowRank(3) = %d, %v, %v; want 2, true", rank, ok, err)
+	}
+}
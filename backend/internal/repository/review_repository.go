@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"matkis-assignment/backend/internal/models"
+)
+
+var (
+	ErrReviewNotFound = errors.New("review not found")
+	ErrReviewResolved = errors.New("review has already been resolved")
+)
+
+const reviewColumns = "id, user_id, old_rating, new_rating, user_version, reasons, status, created_at, resolved_at"
+
+func scanReview(row scanner) (*models.RatingReview, error) {
+	review := &models.RatingReview{}
+	var resolvedAt sql.NullTime
+	err := row.Scan(
+		&review.ID, &review.UserID, &review.OldRating, &review.NewRating, &review.UserVersion,
+		pq.Array(&review.Reasons), &review.Status, &review.CreatedAt, &resolvedAt,
+	)
+	if resolvedAt.Valid {
+		review.ResolvedAt = &resolvedAt.Time
+	}
+	return review, err
+}
+
+type ReviewRepository struct {
+	db *sql.DB
+}
+
+func NewReviewRepository(db *sql.DB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+func (r *ReviewRepository) Create(ctx context.Context, review *models.RatingReview) error {
+	query := `
+		INSERT INTO rating_reviews (user_id, old_rating, new_rating, user_version, reasons)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		review.UserID, review.OldRating, review.NewRating, review.UserVersion, pq.Array(review.Reasons),
+	).Scan(&review.ID, &review.Status, &review.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create review: %w", err)
+	}
+	return nil
+}
+
+// ListPending returns reviews awaiting a decision, oldest first
+func (r *ReviewRepository) ListPending(ctx context.Context, limit, offset int) ([]*models.RatingReview, error) {
+	query := `
+		SELECT ` + reviewColumns + `
+		FROM rating_reviews
+		WHERE status = 'pending'
+		ORDER BY created_at, id
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []*models.RatingReview{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return reviews, nil
+}
+
+// Resolve moves a pending review to status. Only one caller can resolve a
+// review; everyone else gets ErrReviewResolved.
+func (r *ReviewRepository) Resolve(ctx context.Context, id int64, status string) (*models.RatingReview, error) {
+	query := `
+		UPDATE rating_reviews SET status = $1, resolved_at = NOW()
+		WHERE id = $2 AND status = 'pending'
+		RETURNING ` + reviewColumns
+	review, err := scanReview(r.db.QueryRowContext(ctx, query, status, id))
+	if err == nil {
+		return review, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to resolve review: %w", err)
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM rating_reviews WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+	if !exists {
+		return nil, ErrReviewNotFound
+	}
+	return nil, ErrReviewResolved
+}
+
+// Reopen puts a resolved review back in the pending queue
+func (r *ReviewRepository) Reopen(ctx context.Context, id int64) error {
+	query := `UPDATE rating_reviews SET status = 'pending', resolved_at = NULL WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to reopen review: %w", err)
+	}
+	return nil
+}
+
+// ListForUser returns every review raised for a user, oldest first
+func (r *ReviewRepository) ListForUser(ctx context.Context, userID int64) ([]*models.RatingReview, error) {
+	query := `SELECT ` + reviewColumns + ` FROM rating_reviews WHERE user_id = $1 ORDER BY created_at, id`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []*models.RatingReview{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"matkis-assignment/backend/internal/models"
+)
+
+var (
+	ErrTeamNotFound  = errors.New("team not found")
+	ErrTeamNameTaken = errors.New("team name already taken")
+	ErrAlreadyInTeam = errors.New("user already belongs to a team")
+	ErrNotTeamMember = errors.New("user is not a member of this team")
+)
+
+type TeamRepository struct {
+	db *sql.DB
+}
+
+func NewTeamRepository(db *sql.DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+func (r *TeamRepository) Create(ctx context.Context, team *models.Team) error {
+	query := `
+		INSERT INTO teams (name)
+		VALUES ($1)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, team.Name).Scan(&team.ID, &team.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrTeamNameTaken
+		}
+		return fmt.Errorf("failed to create team: %w", err)
+	}
+	return nil
+}
+
+func (r *TeamRepository) GetByID(ctx context.Context, id int64) (*models.Team, error) {
+	team := &models.Team{}
+	query := `SELECT id, name, created_at FROM teams WHERE id = $1`
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&team.ID, &team.Name, &team.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTeamNotFound
+		}
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	return team, nil
+}
+
+// GetByIDs returns teams keyed by ID
+func (r *TeamRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]*models.Team, error) {
+	teams := make(map[int64]*models.Team)
+	if len(ids) == 0 {
+		return teams, nil
+	}
+
+	query := `SELECT id, name, created_at FROM teams WHERE id = ANY($1)`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teams by IDs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		team := &models.Team{}
+		if err := rows.Scan(&team.ID, &team.Name, &team.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %w", err)
+		}
+		teams[team.ID] = team
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return teams, nil
+}
+
+func (r *TeamRepository) AddMember(ctx context.Context, teamID, userID int64) error {
+	query := `INSERT INTO team_members (team_id, user_id) VALUES ($1, $2)`
+	if _, err := r.db.ExecContext(ctx, query, teamID, userID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return ErrAlreadyInTeam
+			case "23503":
+				if pqErr.Constraint == "team_members_team_id_fkey" {
+					return ErrTeamNotFound
+				}
+				return ErrUserNotFound
+			}
+		}
+		return fmt.Errorf("failed to add team member: %w", err)
+	}
+	return nil
+}
+
+func (r *TeamRepository) RemoveMember(ctx context.Context, teamID, userID int64) error {
+	query := `DELETE FROM team_members WHERE team_id = $1 AND user_id = $2`
+	result, err := r.db.ExecContext(ctx, query, teamID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove team member: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotTeamMember
+	}
+	return nil
+}
+
+func (r *TeamRepository) ListMemberIDs(ctx context.Context, teamID int64) ([]int64, error) {
+	query := `SELECT user_id FROM team_members WHERE team_id = $1 ORDER BY joined_at`
+	rows, err := r.db.QueryContext(ctx, query, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list team members: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan team member: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return ids, nil
+}
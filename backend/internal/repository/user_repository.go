@@ -3,12 +3,36 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/lib/pq"
 	"matkis-assignment/backend/internal/models"
 )
 
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUsernameTaken   = errors.New("username already taken")
+	ErrVersionConflict = errors.New("user has been modified since the expected version")
+)
+
+// userColumns is the column list every user query selects, in scanUser order
+const userColumns = "id, username, rating, region, games_played, provisional, status, version, created_at, updated_at"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID, &user.Username, &user.Rating, &user.Region, &user.GamesPlayed, &user.Provisional, &user.Status, &user.Version,
+		&user.CreatedAt, &user.UpdatedAt,
+	)
+	return user, err
+}
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -19,58 +43,326 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (username, rating)
-		VALUES ($1, $2)
-		RETURNING id, created_at, updated_at
+		INSERT INTO users (username, rating, region, provisional)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, version, created_at, updated_at
 	`
-	err := r.db.QueryRowContext(ctx, query, user.Username, user.Rating).Scan(
-		&user.ID, &user.CreatedAt, &user.UpdatedAt,
+	err := r.db.QueryRowContext(ctx, query, user.Username, user.Rating, user.Region, user.Provisional).Scan(
+		&user.ID, &user.Version, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrUsernameTaken
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
 }
 
+// CreateMany inserts users with distinct usernames in one statement. Each
+// created user is replaced in the slice by its stored row; users whose
+// username is already taken are skipped, left as they were and returned.
+func (r *UserRepository) CreateMany(ctx context.Context, users []*models.User) ([]*models.User, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+
+	values := make([]string, len(users))
+	args := make([]interface{}, 0, len(users)*4)
+	for i, user := range users {
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d)", i*4+1, i*4+2, i*4+3, i*4+4)
+		args = append(args, user.Username, user.Rating, user.Region, user.Provisional)
+	}
+
+	query := `
+		INSERT INTO users (username, rating, region, provisional)
+		VALUES ` + strings.Join(values, ", ") + `
+		ON CONFLICT (username) DO NOTHING
+		RETURNING ` + userColumns
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create users: %w", err)
+	}
+	defer rows.Close()
+
+	created := make(map[string]*models.User, len(users))
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		created[user.Username] = user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	var skipped []*models.User
+	for i, user := range users {
+		if row, ok := created[user.Username]; ok {
+			users[i] = row
+		} else {
+			skipped = append(skipped, user)
+		}
+	}
+	return skipped, nil
+}
+
 func (r *UserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
-	user := &models.User{}
-	query := `SELECT id, username, rating, created_at, updated_at FROM users WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID, &user.Username, &user.Rating, &user.CreatedAt, &user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// Rename changes a user's username and records the old one in username_history
+func (r *UserRepository) Rename(ctx context.Context, id int64, username string) (*models.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := scanUser(tx.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1 FOR UPDATE`, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	// Nothing to change or record
+	if current.Username == username {
+		if err := tx.Rollback(); err != nil {
+			return nil, fmt.Errorf("failed to end rename: %w", err)
+		}
+		return current, nil
+	}
+	oldUsername := current.Username
+
+	query := `UPDATE users SET username = $1 WHERE id = $2 RETURNING ` + userColumns
+	user, err := scanUser(tx.QueryRowContext(ctx, query, username, id))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrUsernameTaken
+		}
+		return nil, fmt.Errorf("failed to rename user: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO username_history (user_id, old_username, new_username) VALUES ($1, $2, $3)`,
+		id, oldUsername, username,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record username change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit rename: %w", err)
+	}
 	return user, nil
 }
 
-func (r *UserRepository) UpdateRating(ctx context.Context, id int64, rating int) error {
+// UsernameHistory returns a user's username changes, oldest first
+func (r *UserRepository) UsernameHistory(ctx context.Context, id int64) ([]models.UsernameChange, error) {
+	query := `
+		SELECT old_username, new_username, changed_at
+		FROM username_history
+		WHERE user_id = $1
+		ORDER BY changed_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get username history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.UsernameChange{}
+	for rows.Next() {
+		var change models.UsernameChange
+		if err := rows.Scan(&change.OldUsername, &change.NewUsername, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan username change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return changes, nil
+}
+
+// Delete removes a user. Friendships, team membership, matches and history
+// are removed with it by the foreign keys.
+func (r *UserRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateRating sets a new rating, counts it as a rated game, bumps the row
+// version and returns the updated user. A non-zero expectedVersion makes the
+// update conditional; ErrVersionConflict is returned if it doesn't match.
+func (r *UserRepository) UpdateRating(ctx context.Context, id int64, rating int, expectedVersion int64) (*models.User, error) {
 	if rating < 100 || rating > 5000 {
-		return fmt.Errorf("rating must be between 100 and 5000")
+		return nil, fmt.Errorf("rating must be between 100 and 5000")
 	}
-	query := `UPDATE users SET rating = $1 WHERE id = $2`
-	result, err := r.db.ExecContext(ctx, query, rating, id)
+	query := `
+		UPDATE users SET rating = $1, games_played = games_played + 1, version = version + 1
+		WHERE id = $2 AND ($3 = 0 OR version = $3)
+		RETURNING ` + userColumns
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, rating, id, expectedVersion))
 	if err != nil {
-		return fmt.Errorf("failed to update rating: %w", err)
+		if err == sql.ErrNoRows {
+			if expectedVersion == 0 {
+				return nil, ErrUserNotFound
+			}
+			if _, err := r.GetByID(ctx, id); err != nil {
+				return nil, err
+			}
+			return nil, ErrVersionConflict
+		}
+		return nil, fmt.Errorf("failed to update rating: %w", err)
 	}
-	rowsAffected, err := result.RowsAffected()
+	return user, nil
+}
+
+// RatingUpdate is one row of a batch rating update
+type RatingUpdate struct {
+	UserID          int64
+	Rating          int
+	ExpectedVersion int64 // 0 for an unconditional update
+}
+
+// UpdateRatings applies a batch of rating updates in one statement, with the
+// same effect per row as UpdateRating. Rows whose expected version no longer
+// matches are skipped and left out of the result. With atomic set, the
+// transaction is rolled back and ErrVersionConflict returned instead if any
+// row was skipped.
+func (r *UserRepository) UpdateRatings(ctx context.Context, updates []RatingUpdate, atomic bool) (map[int64]*models.User, error) {
+	users := make(map[int64]*models.User, len(updates))
+	if len(updates) == 0 {
+		return users, nil
+	}
+
+	values := make([]string, len(updates))
+	args := make([]interface{}, 0, len(updates)*3)
+	for i, u := range updates {
+		if u.Rating < 100 || u.Rating > 5000 {
+			return nil, fmt.Errorf("rating must be between 100 and 5000")
+		}
+		values[i] = fmt.Sprintf("($%d::bigint, $%d::integer, $%d::bigint)", i*3+1, i*3+2, i*3+3)
+		args = append(args, u.UserID, u.Rating, u.ExpectedVersion)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE users
+		SET rating = v.new_rating, games_played = games_played + 1, version = version + 1
+		FROM (VALUES ` + strings.Join(values, ", ") + `) AS v(user_id, new_rating, expected_version)
+		WHERE users.id = v.user_id AND (v.expected_version = 0 OR users.version = v.expected_version)
+		RETURNING ` + userColumns
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update ratings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users[user.ID] = user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	if atomic && len(users) < len(updates) {
+		return nil, ErrVersionConflict
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit rating updates: %w", err)
+	}
+	return users, nil
+}
+
+// MarkEstablished clears a user's provisional flag
+func (r *UserRepository) MarkEstablished(ctx context.Context, id int64) error {
+	query := `UPDATE users SET provisional = FALSE WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark user established: %w", err)
 	}
 	return nil
 }
 
+// SetStatus changes a user's moderation state and returns the updated user
+func (r *UserRepository) SetStatus(ctx context.Context, id int64, status string) (*models.User, error) {
+	query := `UPDATE users SET status = $1 WHERE id = $2 RETURNING ` + userColumns
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, status, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to set user status: %w", err)
+	}
+	return user, nil
+}
+
+// ListModerated returns users in any moderation state other than active
+func (r *UserRepository) ListModerated(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE status <> 'active'
+		ORDER BY updated_at DESC, id
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list moderated users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return users, nil
+}
+
 func (r *UserRepository) SearchByPrefix(ctx context.Context, prefix string, limit int) ([]*models.User, error) {
 	query := `
-		SELECT id, username, rating, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE username LIKE $1
+		WHERE username LIKE $1 AND status IN ('active', 'shadow_banned')
 		ORDER BY username
 		LIMIT $2
 	`
@@ -82,10 +374,8 @@ func (r *UserRepository) SearchByPrefix(ctx context.Context, prefix string, limi
 
 	var users []*models.User
 	for rows.Next() {
-		user := &models.User{}
-		if err := rows.Scan(
-			&user.ID, &user.Username, &user.Rating, &user.CreatedAt, &user.UpdatedAt,
-		); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
@@ -98,7 +388,7 @@ func (r *UserRepository) SearchByPrefix(ctx context.Context, prefix string, limi
 
 func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.User, error) {
 	query := `
-		SELECT id, username, rating, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		ORDER BY rating DESC, username ASC
 		LIMIT $1 OFFSET $2
@@ -111,10 +401,8 @@ func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]*mode
 
 	var users []*models.User
 	for rows.Next() {
-		user := &models.User{}
-		if err := rows.Scan(
-			&user.ID, &user.Username, &user.Rating, &user.CreatedAt, &user.UpdatedAt,
-		); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
@@ -133,7 +421,7 @@ func (r *UserRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.U
 	// Build query with IN clause using pq.Array
 	// PostgreSQL requires array to be passed as pq.Array for ANY() operator
 	query := `
-		SELECT id, username, rating, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE id = ANY($1)
 	`
@@ -148,10 +436,8 @@ func (r *UserRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.U
 	// Create map for quick lookup
 	userMap := make(map[int64]*models.User)
 	for rows.Next() {
-		user := &models.User{}
-		if err := rows.Scan(
-			&user.ID, &user.Username, &user.Rating, &user.CreatedAt, &user.UpdatedAt,
-		); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		userMap[user.ID] = user
@@ -171,6 +457,15 @@ func (r *UserRepository) GetByIDs(ctx context.Context, ids []int64) ([]*models.U
 	return result, nil
 }
 
+func (r *UserRepository) AverageRating(ctx context.Context) (float64, error) {
+	var avg sql.NullFloat64
+	query := `SELECT AVG(rating) FROM users WHERE NOT provisional AND status = 'active'`
+	if err := r.db.QueryRowContext(ctx, query).Scan(&avg); err != nil {
+		return 0, fmt.Errorf("failed to get average rating: %w", err)
+	}
+	return avg.Float64, nil
+}
+
 func (r *UserRepository) Count(ctx context.Context) (int, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM users`
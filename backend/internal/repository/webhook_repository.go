@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"matkis-assignment/backend/internal/models"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrDeliveryNotDead  = errors.New("only dead deliveries can be retried")
+)
+
+const webhookColumns = "id, url, event_types, secret, active, created_at"
+
+const deliveryColumns = "id, webhook_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at"
+
+func scanWebhook(row scanner) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := row.Scan(
+		&webhook.ID, &webhook.URL, pq.Array(&webhook.EventTypes), &webhook.Secret, &webhook.Active, &webhook.CreatedAt,
+	)
+	return webhook, err
+}
+
+func scanDelivery(row scanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var payload []byte
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.EventType, &payload, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt,
+	)
+	delivery.Payload = payload
+	return delivery, err
+}
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (url, event_types, secret, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		webhook.URL, pq.Array(webhook.EventTypes), webhook.Secret, webhook.Active,
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id int64) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]*models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return webhooks, nil
+}
+
+// Update saves a webhook's URL, event types and active flag
+func (r *WebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	query := `UPDATE webhooks SET url = $1, event_types = $2, active = $3 WHERE id = $4`
+	result, err := r.db.ExecContext(ctx, query, webhook.URL, pq.Array(webhook.EventTypes), webhook.Active, webhook.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// SubscribedTypes returns the event types at least one active webhook wants
+func (r *WebhookRepository) SubscribedTypes(ctx context.Context) (map[string]bool, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT unnest(event_types) FROM webhooks WHERE active`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscribed event types: %w", err)
+	}
+	defer rows.Close()
+
+	types := make(map[string]bool)
+	for rows.Next() {
+		var eventType string
+		if err := rows.Scan(&eventType); err != nil {
+			return nil, fmt.Errorf("failed to scan event type: %w", err)
+		}
+		types[eventType] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return types, nil
+}
+
+// Enqueue queues an event for every active webhook subscribed to its type.
+// A non-zero webhookID queues it for that webhook alone, subscribed or not.
+// It returns the number queued.
+func (r *WebhookRepository) Enqueue(ctx context.Context, eventType string, payload []byte, webhookID int64) (int64, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+		SELECT id, $1, $2 FROM webhooks
+		WHERE CASE WHEN $3 = 0 THEN active AND $1 = ANY(event_types) ELSE id = $3 END
+	`
+	result, err := r.db.ExecContext(ctx, query, eventType, payload, webhookID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	queued, _ := result.RowsAffected()
+	return queued, nil
+}
+
+// ClaimedDelivery is a due delivery with what's needed to send it
+type ClaimedDelivery struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// ClaimDue takes up to limit due deliveries and pushes their next attempt
+// back by lease, so other workers skip them while they are sent. If the
+// worker dies, they become due again once the lease runs out.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*ClaimedDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+			d.last_error, d.created_at, d.delivered_at, w.url, w.secret
+	`
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	claimed := []*ClaimedDelivery{}
+	for rows.Next() {
+		delivery := &ClaimedDelivery{}
+		var payload []byte
+		err := rows.Scan(
+			&delivery.ID, &delivery.WebhookID, &delivery.EventType, &payload, &delivery.Status, &delivery.Attempts,
+			&delivery.NextAttemptAt, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt,
+			&delivery.URL, &delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		delivery.Payload = payload
+		claimed = append(claimed, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return claimed, nil
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id int64) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, delivered_at = NOW(), last_error = NULL
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark delivery delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt, scheduling the next one after retryIn,
+// or moving the delivery to the dead letters if dead is set
+func (r *WebhookRepository) MarkFailed(ctx context.Context, id int64, reason string, retryIn time.Duration, dead bool) error {
+	status := models.DeliveryPending
+	if dead {
+		status = models.DeliveryDead
+	}
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = attempts + 1, last_error = $2,
+			next_attempt_at = NOW() + $3 * INTERVAL '1 millisecond'
+		WHERE id = $4
+	`
+	if _, err := r.db.ExecContext(ctx, query, status, reason, retryIn.Milliseconds(), id); err != nil {
+		return fmt.Errorf("failed to record delivery failure: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns a webhook's deliveries, newest first, optionally
+// only those with the given status
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID int64, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, webhookID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver puts a dead delivery back in the queue with a fresh set of attempts
+func (r *WebhookRepository) Redeliver(ctx context.Context, webhookID, id int64) (*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND webhook_id = $2 AND status = 'dead'
+		RETURNING ` + deliveryColumns
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, query, id, webhookID))
+	if err == nil {
+		return delivery, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to redeliver: %w", err)
+	}
+
+	var exists bool
+	err = r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2)`, id, webhookID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	if !exists {
+		return nil, ErrDeliveryNotFound
+	}
+	return nil, ErrDeliveryNotDead
+}
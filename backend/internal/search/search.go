@@ -6,21 +6,25 @@ import (
 	"matkis-assignment/backend/internal/models"
 	"matkis-assignment/backend/internal/ranking"
 	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/tier"
 )
 
 type SearchService struct {
-	userRepo  *repository.UserRepository
+	userRepo    *repository.UserRepository
 	rankService *ranking.RankingService
+	tierService *tier.Service
 }
 
-func NewSearchService(userRepo *repository.UserRepository, rankService *ranking.RankingService) *SearchService {
+func NewSearchService(userRepo *repository.UserRepository, rankService *ranking.RankingService, tierService *tier.Service) *SearchService {
 	return &SearchService{
 		userRepo:    userRepo,
 		rankService: rankService,
+		tierService: tierService,
 	}
 }
 
-// SearchUsers performs prefix search and returns users with their global ranks
+// SearchUsers performs prefix search over active and shadow-banned users and
+// returns them with their global ranks
 func (s *SearchService) SearchUsers(ctx context.Context, prefix string, limit int) ([]*models.UserWithRank, error) {
 	// Search users by prefix in PostgreSQL
 	users, err := s.userRepo.SearchByPrefix(ctx, prefix, limit)
@@ -44,17 +48,35 @@ func (s *SearchService) SearchUsers(ctx context.Context, prefix string, limit in
 		return nil, err
 	}
 
-	// Combine user data with ranks
+	regionalRanks, err := s.rankService.GetRegionalRanksForUsers(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tiers, err := s.tierService.Resolver(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Combine user data with ranks. Shadow-banned users show the ranks they
+	// would have, as on their profile.
 	result := make([]*models.UserWithRank, len(users))
 	for i, user := range users {
-		rank, exists := ranks[user.ID]
-		if !exists {
-			rank = 0 // User not in leaderboard
+		rank, regionalRank := ranks[user.ID], regionalRanks[user.ID]
+		if user.Status == models.StatusShadowBanned {
+			if rank, _, err = s.rankService.ShadowRank(ctx, user.ID); err != nil {
+				return nil, err
+			}
+			if regionalRank, _, err = s.rankService.RegionalShadowRank(ctx, user.ID); err != nil {
+				return nil, err
+			}
 		}
 
 		result[i] = &models.UserWithRank{
-			User:      *user,
-			GlobalRank: rank,
+			User:         user.Public(),
+			GlobalRank:   rank,
+			RegionalRank: regionalRank,
+			Tier:         tiers.TierFor(user.Rating),
 		}
 	}
 
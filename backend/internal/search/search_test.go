@@ -0,0 +1,74 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+	"matkis-assignment/backend/internal/tier"
+)
+
+func TestSearchUsersHidesShadowBan(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	rankService := ranking.NewRankingService(client)
+	for userID, rating := range map[int64]int{1: 2400, 2: 2000, 3: 2200} {
+		if err := rankService.UpdateUserRating(ctx, userID, rating, 0); err != nil {
+			t.Fatal(err)
+		}
+		region := "DE"
+		if userID == 3 {
+			region = "FR"
+		}
+		if err := rankService.SetUserRegion(ctx, userID, region); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rankService.Moderate(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSearchService(repository.NewUserRepository(db), rankService,
+		tier.NewService(tier.DefaultTable, rankService, events.NewBus(client)))
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Hidden and banned users are filtered out by the query
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "rating", "region", "games_played", "provisional", "status", "version", "created_at", "updated_at",
+	}).AddRow(2, "player2", 2000, "DE", 10, false, models.StatusShadowBanned, 1, at, at)
+	mock.ExpectQuery(regexp.QuoteMeta("status IN ('active', 'shadow_banned')")).
+		WithArgs("player%", 10).WillReturnRows(rows)
+
+	results, err := s.SearchUsers(ctx, "player", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results = %+v, want one user", results)
+	}
+	got := results[0]
+	if got.Status != models.StatusActive || got.GlobalRank != 3 || got.RegionalRank != 2 {
+		t.Errorf("result = %+v, want active, ranked 3 globally and 2 in DE", got)
+	}
+}
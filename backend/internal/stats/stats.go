@@ -0,0 +1,156 @@
+package stats
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+const (
+	MinRating = 100
+	MaxRating = 5000
+)
+
+// Percentiles are the "top N%" cut-offs reported with every stats response
+var Percentiles = []float64{1, 5, 10, 25, 50, 75, 90}
+
+type Bucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+type Cutoff struct {
+	TopPercent float64 `json:"top_percent"`
+	MinRating  int     `json:"min_rating"`
+}
+
+type Stats struct {
+	TotalPlayers int       `json:"total_players"`
+	MeanRating   float64   `json:"mean_rating"`
+	MedianRating float64   `json:"median_rating"`
+	BucketSize   int       `json:"bucket_size"`
+	Histogram    []Bucket  `json:"histogram"`
+	Percentiles  []Cutoff  `json:"percentiles"`
+	ComputedAt   time.Time `json:"computed_at"`
+}
+
+type cacheEntry struct {
+	stats   *Stats
+	expires time.Time
+}
+
+// Service computes rating distribution statistics and caches them per bucket
+// size for a short TTL
+type Service struct {
+	rankService *ranking.RankingService
+	userRepo    *repository.UserRepository
+	ttl         time.Duration
+
+	mu    sync.Mutex
+	cache map[int]cacheEntry
+}
+
+func NewService(rankService *ranking.RankingService, userRepo *repository.UserRepository, ttl time.Duration) *Service {
+	return &Service{
+		rankService: rankService,
+		userRepo:    userRepo,
+		ttl:         ttl,
+		cache:       make(map[int]cacheEntry),
+	}
+}
+
+// GetStats returns statistics with a histogram of bucketSize-wide rating buckets
+func (s *Service) GetStats(ctx context.Context, bucketSize int) (*Stats, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[bucketSize]
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.stats, nil
+	}
+
+	stats, err := s.compute(ctx, bucketSize)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[bucketSize] = cacheEntry{stats: stats, expires: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+	return stats, nil
+}
+
+func (s *Service) compute(ctx context.Context, bucketSize int) (*Stats, error) {
+	total, err := s.rankService.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		TotalPlayers: total,
+		BucketSize:   bucketSize,
+		Histogram:    []Bucket{},
+		Percentiles:  []Cutoff{},
+		ComputedAt:   time.Now().UTC(),
+	}
+	if total == 0 {
+		return stats, nil
+	}
+
+	if stats.MeanRating, err = s.userRepo.AverageRating(ctx); err != nil {
+		return nil, err
+	}
+	if stats.MedianRating, err = s.median(ctx, total); err != nil {
+		return nil, err
+	}
+
+	// Histogram buckets via ZCOUNT ranges
+	var ranges [][2]int
+	for min := MinRating; min <= MaxRating; min += bucketSize {
+		max := min + bucketSize - 1
+		if max > MaxRating {
+			max = MaxRating
+		}
+		ranges = append(ranges, [2]int{min, max})
+	}
+	counts, err := s.rankService.CountInRanges(ctx, ranges)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range ranges {
+		stats.Histogram = append(stats.Histogram, Bucket{Min: r[0], Max: r[1], Count: counts[i]})
+	}
+
+	// Rating needed to be in the top N%
+	for _, pct := range Percentiles {
+		position := int(math.Ceil(float64(total)*pct/100)) - 1
+		rating, ok, err := s.rankService.RatingAtPosition(ctx, position)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			stats.Percentiles = append(stats.Percentiles, Cutoff{TopPercent: pct, MinRating: rating})
+		}
+	}
+
+	return stats, nil
+}
+
+func (s *Service) median(ctx context.Context, total int) (float64, error) {
+	upper, _, err := s.rankService.RatingAtPosition(ctx, total/2)
+	if err != nil {
+		return 0, err
+	}
+	if total%2 == 1 {
+		return float64(upper), nil
+	}
+	lower, _, err := s.rankService.RatingAtPosition(ctx, total/2-1)
+	if err != nil {
+		return 0, err
+	}
+	return float64(upper+lower) / 2, nil
+}
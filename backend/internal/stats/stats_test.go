@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func TestGetStats(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rankService := ranking.NewRankingService(client)
+	ctx := context.Background()
+	for i, rating := range []int{100, 900, 1000, 1500, 2000, 2500, 3000, 4999} {
+		if err := rankService.UpdateUserRating(ctx, int64(i+1), rating, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The mean comes from PostgreSQL, once: the second call is cached
+	mock.ExpectQuery("SELECT AVG").WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(1999.875))
+
+	s := NewService(rankService, repository.NewUserRepository(db), time.Minute)
+	stats, err := s.GetStats(ctx, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := s.GetStats(ctx, 1000); err != nil || again != stats {
+		t.Errorf("second call = %p, %v; want the cached %p", again, err, stats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if stats.TotalPlayers != 8 || stats.MeanRating != 1999.875 || stats.MedianRating != 1750 {
+		t.Errorf("total, mean, median = %d, %v, %v; want 8, 1999.875, 1750",
+			stats.TotalPlayers, stats.MeanRating, stats.MedianRating)
+	}
+
+	wantCounts := []int{3, 2, 2, 0, 1}
+	if len(stats.Histogram) != len(wantCounts) {
+		t.Fatalf("histogram = %+v, want %d buckets", stats.Histogram, len(wantCounts))
+	}
+	for i, count := range wantCounts {
+		if stats.Histogram[i].Count != count {
+			t.Errorf("bucket %+v count = %d, want %d", stats.Histogram[i], stats.Histogram[i].Count, count)
+		}
+	}
+	if last := stats.Histogram[len(stats.Histogram)-1]; last.Min != 4100 || last.Max != MaxRating {
+		t.Errorf("last bucket = %+v, want 4100-%d", last, MaxRating)
+	}
+
+	// Top 1%, 5% and 10% of 8 players is the best one; top 25% the best two
+	cutoffs := map[float64]int{1: 4999, 5: 4999, 10: 4999, 25: 3000, 50: 2000, 75: 1000, 90: 100}
+	if len(stats.Percentiles) != len(cutoffs) {
+		t.Fatalf("percentiles = %+v, want %d", stats.Percentiles, len(cutoffs))
+	}
+	for _, cutoff := range stats.Percentiles {
+		if cutoffs[cutoff.TopPercent] != cutoff.MinRating {
+			t.Errorf("top %v%% = %d, want %d", cutoff.TopPercent, cutoff.MinRating, cutoffs[cutoff.TopPercent])
+		}
+	}
+}
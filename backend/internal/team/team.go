@@ -0,0 +1,255 @@
+package team
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+// TeamsKey is the sorted set of team ID to aggregate score
+const TeamsKey = "leaderboard:teams"
+
+// MembershipKey is a hash of user ID to team ID
+const MembershipKey = "team:membership"
+
+func membersKey(teamID int64) string {
+	return fmt.Sprintf("team:%d:members", teamID)
+}
+
+func statsKey(teamID int64) string {
+	return fmt.Sprintf("team:%d:stats", teamID)
+}
+
+type Aggregate string
+
+const (
+	AggregateSum     Aggregate = "sum"
+	AggregateAverage Aggregate = "avg"
+	AggregateTopK    Aggregate = "topk"
+)
+
+// ParseAggregate validates a TEAM_AGGREGATE value
+func ParseAggregate(value string) (Aggregate, error) {
+	switch Aggregate(value) {
+	case AggregateSum, AggregateAverage, AggregateTopK:
+		return Aggregate(value), nil
+	case "":
+		return AggregateAverage, nil
+	}
+	return "", fmt.Errorf("unknown team aggregate %q: expected sum, avg or topk", value)
+}
+
+// updateScript applies one member's rating change (or removal when the rating
+// is negative) to the team's member set and running sum, then recomputes the
+// team's score in the same round trip.
+//
+// KEYS: members set, stats hash, teams set
+// ARGV: user ID, rating, team ID, aggregate, k
+var updateScript = redis.NewScript(`
+local old = redis.call('ZSCORE', KEYS[1], ARGV[1])
+local rating = tonumber(ARGV[2])
+if rating < 0 then
+	if old then
+		redis.call('ZREM', KEYS[1], ARGV[1])
+		redis.call('HINCRBY', KEYS[2], 'sum', -tonumber(old))
+		redis.call('HINCRBY', KEYS[2], 'count', -1)
+	end
+else
+	redis.call('ZADD', KEYS[1], rating, ARGV[1])
+	if old then
+		redis.call('HINCRBY', KEYS[2], 'sum', rating - tonumber(old))
+	else
+		redis.call('HINCRBY', KEYS[2], 'sum', rating)
+		redis.call('HINCRBY', KEYS[2], 'count', 1)
+	end
+end
+
+local count = tonumber(redis.call('HGET', KEYS[2], 'count') or '0')
+if count <= 0 then
+	redis.call('DEL', KEYS[2])
+	redis.call('ZREM', KEYS[3], ARGV[3])
+	return 0
+end
+
+local score
+if ARGV[4] == 'sum' then
+	score = tonumber(redis.call('HGET', KEYS[2], 'sum'))
+elseif ARGV[4] == 'avg' then
+	score = tonumber(redis.call('HGET', KEYS[2], 'sum')) / count
+else
+	local top = redis.call('ZREVRANGE', KEYS[1], 0, tonumber(ARGV[5]) - 1, 'WITHSCORES')
+	local total = 0
+	for i = 2, #top, 2 do
+		total = total + tonumber(top[i])
+	end
+	score = total / (#top / 2)
+end
+redis.call('ZADD', KEYS[3], score, ARGV[3])
+return 1
+`)
+
+type Service struct {
+	redis       *redis.Client
+	rankService *ranking.RankingService
+	aggregate   Aggregate
+	topK        int
+}
+
+// NewService creates a team service and subscribes it to rating changes so
+// team scores follow their members' ratings
+func NewService(redis *redis.Client, rankService *ranking.RankingService, aggregate Aggregate, topK int) *Service {
+	if topK < 1 {
+		topK = 5
+	}
+	s := &Service{
+		redis:       redis,
+		rankService: rankService,
+		aggregate:   aggregate,
+		topK:        topK,
+	}
+	rankService.AddListener(s.handleRatingChange)
+	return s
+}
+
+// Aggregate returns how member ratings are combined into a team score
+func (s *Service) Aggregate() Aggregate {
+	return s.aggregate
+}
+
+// AddMember adds a user's current rating to the team's score
+func (s *Service) AddMember(ctx context.Context, teamID, userID int64) error {
+	member := strconv.FormatInt(userID, 10)
+	if err := s.redis.HSet(ctx, MembershipKey, member, teamID).Err(); err != nil {
+		return fmt.Errorf("failed to record team membership: %w", err)
+	}
+
+	ratings, err := s.rankService.RankAmong(ctx, []int64{userID})
+	if err != nil {
+		return err
+	}
+	if len(ratings) == 0 {
+		// Not rated yet; the first rating change will add them
+		return nil
+	}
+	return s.apply(ctx, teamID, userID, ratings[0].Rating)
+}
+
+// RemoveMember takes a user's rating out of the team's score
+func (s *Service) RemoveMember(ctx context.Context, teamID, userID int64) error {
+	if err := s.redis.HDel(ctx, MembershipKey, strconv.FormatInt(userID, 10)).Err(); err != nil {
+		return fmt.Errorf("failed to remove team membership: %w", err)
+	}
+	return s.apply(ctx, teamID, userID, -1)
+}
+
+// RemoveUser takes a user out of whichever team they belong to
+func (s *Service) RemoveUser(ctx context.Context, userID int64) error {
+	teamID, err := s.redis.HGet(ctx, MembershipKey, strconv.FormatInt(userID, 10)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return nil
+		}
+		return fmt.Errorf("failed to look up team membership: %w", err)
+	}
+	return s.RemoveMember(ctx, teamID, userID)
+}
+
+func (s *Service) apply(ctx context.Context, teamID, userID int64, rating int) error {
+	err := updateScript.Run(ctx, s.redis,
+		[]string{membersKey(teamID), statsKey(teamID), TeamsKey},
+		userID, rating, teamID, string(s.aggregate), s.topK,
+	).Err()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to update team score: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) handleRatingChange(ctx context.Context, change ranking.RatingChange) {
+	teamID, err := s.redis.HGet(ctx, MembershipKey, strconv.FormatInt(change.UserID, 10)).Int64()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Warning: failed to look up team for user %d: %v", change.UserID, err)
+		}
+		return
+	}
+
+	// A user removed from the leaderboard no longer counts towards the team
+	rating := change.NewRating
+	if rating == 0 {
+		rating = -1
+	}
+	if err := s.apply(ctx, teamID, change.UserID, rating); err != nil {
+		log.Printf("Warning: failed to update team %d for user %d: %v", teamID, change.UserID, err)
+	}
+}
+
+type Entry struct {
+	TeamID int64
+	Score  float64
+	Rank   int
+}
+
+// GetLeaderboard gets top N teams with tie-aware ranks
+func (s *Service) GetLeaderboard(ctx context.Context, limit, offset int) ([]Entry, error) {
+	results, err := s.redis.ZRevRangeWithScores(ctx, TeamsKey, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team leaderboard: %w", err)
+	}
+	if len(results) == 0 {
+		return []Entry{}, nil
+	}
+
+	// Rank the first team by counting higher scores so a page starting
+	// inside a tie group gets the group's rank
+	higherCount, err := s.redis.ZCount(ctx, TeamsKey, above(results[0].Score), "+inf").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count higher scores: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(results))
+	currentRank := int(higherCount) + 1
+	for i, result := range results {
+		if i > 0 && result.Score != results[i-1].Score {
+			currentRank = offset + i + 1
+		}
+
+		teamID, err := strconv.ParseInt(fmt.Sprintf("%v", result.Member), 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{TeamID: teamID, Score: result.Score, Rank: currentRank})
+	}
+
+	return entries, nil
+}
+
+// GetTeamStanding returns a team's score and tie-aware rank; ok is false if
+// the team has no rated members
+func (s *Service) GetTeamStanding(ctx context.Context, teamID int64) (float64, int, bool, error) {
+	score, err := s.redis.ZScore(ctx, TeamsKey, strconv.FormatInt(teamID, 10)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, fmt.Errorf("failed to get team score: %w", err)
+	}
+
+	higherCount, err := s.redis.ZCount(ctx, TeamsKey, above(score), "+inf").Result()
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to count higher scores: %w", err)
+	}
+
+	return score, int(higherCount) + 1, true, nil
+}
+
+// above is the exclusive ZCOUNT bound for scores higher than score. Average
+// and top-k scores are fractional, so it keeps every digit: a rounded bound
+// would count the team itself or miss one just above it.
+func above(score float64) string {
+	return "(" + strconv.FormatFloat(score, 'g', -1, 64)
+}
@@ -0,0 +1,119 @@
+package team
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+func newTestService(t *testing.T, aggregate Aggregate, topK int) (*Service, *ranking.RankingService) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	rankService := ranking.NewRankingService(client)
+	return NewService(client, rankService, aggregate, topK), rankService
+}
+
+// join rates each user and adds them to the team
+func join(t *testing.T, s *Service, rankService *ranking.RankingService, teamID int64, ratings map[int64]int) {
+	t.Helper()
+	ctx := context.Background()
+	for userID, rating := range ratings {
+		if err := rankService.UpdateUserRating(ctx, userID, rating, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddMember(ctx, teamID, userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParseAggregate(t *testing.T) {
+	for value, want := range map[string]Aggregate{"": AggregateAverage, "sum": AggregateSum, "avg": AggregateAverage, "topk": AggregateTopK} {
+		if got, err := ParseAggregate(value); err != nil || got != want {
+			t.Errorf("ParseAggregate(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseAggregate("max"); err == nil {
+		t.Error("ParseAggregate(max) accepted an unknown aggregate")
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	tests := []struct {
+		aggregate Aggregate
+		want      float64
+	}{
+		{AggregateSum, 4500},
+		{AggregateAverage, 1500},
+		{AggregateTopK, 1750},
+	}
+
+	for _, tt := range tests {
+		s, rankService := newTestService(t, tt.aggregate, 2)
+		join(t, s, rankService, 1, map[int64]int{1: 1000, 2: 1500, 3: 2000})
+
+		score, rank, ok, err := s.GetTeamStanding(context.Background(), 1)
+		if err != nil || !ok || score != tt.want || rank != 1 {
+			t.Errorf("%s: standing = %v, %d, %v, %v; want %v, 1, true", tt.aggregate, score, rank, ok, err, tt.want)
+		}
+	}
+}
+
+func TestFollowsMemberRatings(t *testing.T) {
+	s, rankService := newTestService(t, AggregateSum, 0)
+	ctx := context.Background()
+	join(t, s, rankService, 1, map[int64]int{1: 1000, 2: 1500})
+
+	if err := rankService.UpdateUserRating(ctx, 1, 1200, 0); err != nil {
+		t.Fatal(err)
+	}
+	if score, _, _, _ := s.GetTeamStanding(ctx, 1); score != 2700 {
+		t.Errorf("score after a rating change = %v, want 2700", score)
+	}
+
+	if err := s.RemoveUser(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if score, _, _, _ := s.GetTeamStanding(ctx, 1); score != 1200 {
+		t.Errorf("score after a member left = %v, want 1200", score)
+	}
+
+	if err := s.RemoveUser(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok, err := s.GetTeamStanding(ctx, 1); ok || err != nil {
+		t.Errorf("empty team still has a standing: %v, %v", ok, err)
+	}
+}
+
+func TestFractionalScoreRanks(t *testing.T) {
+	s, rankService := newTestService(t, AggregateAverage, 0)
+	ctx := context.Background()
+
+	// Averages of 1333.33..., 1666.66... and 1333.33... again; rounding them
+	// to six places would count a team as above itself, or miss one above it
+	join(t, s, rankService, 1, map[int64]int{1: 1000, 2: 1000, 3: 2000})
+	join(t, s, rankService, 2, map[int64]int{4: 1000, 5: 2000, 6: 2000})
+	join(t, s, rankService, 3, map[int64]int{7: 1000, 8: 1000, 9: 2000})
+
+	for teamID, want := range map[int64]int{1: 2, 2: 1, 3: 2} {
+		_, rank, ok, err := s.GetTeamStanding(ctx, teamID)
+		if err != nil || !ok || rank != want {
+			t.Errorf("rank of team %d = %d, %v, %v; want %d", teamID, rank, ok, err, want)
+		}
+	}
+
+	entries, err := s.GetLeaderboard(ctx, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Rank != 2 || entries[1].Rank != 2 {
+		t.Errorf("page 2 = %+v, want two teams ranked 2", entries)
+	}
+}
@@ -0,0 +1,321 @@
+package tier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+// Tier is a named division. A tier is either reached by rating (MinRating) or
+// by standing (Percentile, e.g. 1 for "top 1%").
+type Tier struct {
+	Name       string
+	MinRating  int
+	Percentile float64
+}
+
+// Table lists tiers from lowest to highest
+type Table []Tier
+
+// DefaultTable is used when no TIERS configuration is provided
+var DefaultTable = Table{
+	{Name: "Bronze", MinRating: 100},
+	{Name: "Silver", MinRating: 1200},
+	{Name: "Gold", MinRating: 2000},
+	{Name: "Platinum", MinRating: 2800},
+	{Name: "Diamond", MinRating: 3500},
+	{Name: "Master", MinRating: 4200},
+	{Name: "Grandmaster", MinRating: 4700},
+}
+
+// ParseTable parses a spec such as "Bronze:100,Silver:1200,Master:top1%".
+// Tiers go from lowest to highest: rating thresholds must increase, and any
+// percentile tiers come last with shrinking percentiles. An empty spec
+// returns DefaultTable.
+func ParseTable(spec string) (Table, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return DefaultTable, nil
+	}
+
+	var table Table
+	names := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		name, threshold, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || name == "" || threshold == "" {
+			return nil, fmt.Errorf("invalid tier %q: expected name:rating or name:topN%%", part)
+		}
+		if names[strings.ToLower(name)] {
+			return nil, fmt.Errorf("duplicate tier %q", name)
+		}
+		names[strings.ToLower(name)] = true
+		var prev *Tier
+		if len(table) > 0 {
+			prev = &table[len(table)-1]
+		}
+
+		if strings.HasPrefix(threshold, "top") && strings.HasSuffix(threshold, "%") {
+			pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimPrefix(threshold, "top"), "%"), 64)
+			if err != nil || pct <= 0 || pct > 100 {
+				return nil, fmt.Errorf("invalid percentile for tier %q", name)
+			}
+			// Each higher tier must be a smaller top slice than the one below
+			if prev != nil && prev.Percentile > 0 && pct >= prev.Percentile {
+				return nil, fmt.Errorf("tier %q (top %g%%) must be narrower than %q (top %g%%)", name, pct, prev.Name, prev.Percentile)
+			}
+			table = append(table, Tier{Name: name, Percentile: pct})
+			continue
+		}
+
+		rating, err := strconv.Atoi(threshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rating for tier %q", name)
+		}
+		// A percentile threshold moves with the board, so a fixed rating
+		// can't be checked against it
+		if prev != nil && prev.Percentile > 0 {
+			return nil, fmt.Errorf("rating tier %q cannot follow percentile tier %q", name, prev.Name)
+		}
+		if prev != nil && rating <= prev.MinRating {
+			return nil, fmt.Errorf("tier %q (%d) must have a higher rating than %q (%d)", name, rating, prev.Name, prev.MinRating)
+		}
+		table = append(table, Tier{Name: name, MinRating: rating})
+	}
+
+	if table[0].Percentile > 0 {
+		return nil, fmt.Errorf("lowest tier %q must use a rating threshold", table[0].Name)
+	}
+	return table, nil
+}
+
+// Resolver maps ratings to tiers using thresholds resolved at a point in time
+type Resolver struct {
+	names []string
+	mins  []int
+}
+
+// TierFor returns the highest tier whose threshold the rating reaches
+func (r *Resolver) TierFor(rating int) string {
+	index := r.indexFor(rating)
+	if index < 0 {
+		return ""
+	}
+	return r.names[index]
+}
+
+func (r *Resolver) indexFor(rating int) int {
+	for i := len(r.mins) - 1; i >= 0; i-- {
+		if rating >= r.mins[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// Bounds returns the inclusive rating range covered by the named tier
+func (r *Resolver) Bounds(name string) (int, int, bool) {
+	for i, n := range r.names {
+		if !strings.EqualFold(n, name) {
+			continue
+		}
+		max := math.MaxInt32
+		if i+1 < len(r.mins) {
+			max = r.mins[i+1] - 1
+		}
+		return r.mins[i], max, true
+	}
+	return 0, 0, false
+}
+
+type Service struct {
+	table       Table
+	rankService *ranking.RankingService
+	bus         *events.Bus
+}
+
+// NewService creates a tier service and subscribes it to rating changes so
+// promotion and demotion events are emitted when a boundary is crossed
+func NewService(table Table, rankService *ranking.RankingService, bus *events.Bus) *Service {
+	s := &Service{
+		table:       table,
+		rankService: rankService,
+		bus:         bus,
+	}
+	rankService.AddListener(s.handleRatingChange)
+	return s
+}
+
+// Resolver resolves percentile tiers against the current leaderboard
+func (s *Service) Resolver(ctx context.Context) (*Resolver, error) {
+	return s.resolve(ctx, func(position int) (int, bool, error) {
+		return s.rankService.RatingAtPosition(ctx, position)
+	})
+}
+
+// resolverBefore resolves percentile tiers against the leaderboard as it was
+// before change was written, so a mover's old tier isn't judged against a
+// board that already holds their new rating
+func (s *Service) resolverBefore(ctx context.Context, change ranking.RatingChange) (*Resolver, error) {
+	return s.resolve(ctx, func(position int) (int, bool, error) {
+		return s.ratingBefore(ctx, change, position)
+	})
+}
+
+// resolve builds a Resolver, reading percentile thresholds with ratingAt.
+// The board size is the same before and after a change that moves a user.
+func (s *Service) resolve(ctx context.Context, ratingAt func(position int) (int, bool, error)) (*Resolver, error) {
+	total := -1
+	resolver := &Resolver{
+		names: make([]string, len(s.table)),
+		mins:  make([]int, len(s.table)),
+	}
+
+	for i, t := range s.table {
+		min := t.MinRating
+		if t.Percentile > 0 {
+			if total < 0 {
+				count, err := s.rankService.Count(ctx)
+				if err != nil {
+					return nil, err
+				}
+				total = count
+			}
+
+			// Everyone tied with the last player inside the percentile qualifies
+			min = math.MaxInt32
+			position := int(math.Ceil(float64(total)*t.Percentile/100)) - 1
+			if position >= 0 {
+				rating, ok, err := ratingAt(position)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					min = rating
+				}
+			}
+		}
+
+		// Keep thresholds monotonic so a percentile tier never sits below
+		// the rating tier beneath it
+		if i > 0 && min < resolver.mins[i-1] {
+			min = resolver.mins[i-1]
+		}
+		resolver.names[i] = t.Name
+		resolver.mins[i] = min
+	}
+
+	return resolver, nil
+}
+
+// ratingBefore returns the rating at a position on the board as it was
+// before change. The two boards differ by one entry, so every position moved
+// by at most one: the answer is a neighbour of the current position or the
+// old rating itself, and it is the highest of those with more than position
+// users rated at or above it on the old board.
+func (s *Service) ratingBefore(ctx context.Context, change ranking.RatingChange, position int) (int, bool, error) {
+	candidates := []int{change.OldRating}
+	for p := position - 1; p <= position+1; p++ {
+		if p < 0 {
+			continue
+		}
+		rating, ok, err := s.rankService.RatingAtPosition(ctx, p)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			candidates = append(candidates, rating)
+		}
+	}
+
+	best, found := 0, false
+	for _, rating := range candidates {
+		if found && rating <= best {
+			continue
+		}
+		counts, err := s.rankService.CountInRanges(ctx, [][2]int{{rating, math.MaxInt32}})
+		if err != nil {
+			return 0, false, err
+		}
+		atOrAbove := counts[0]
+		if change.NewRating >= rating {
+			atOrAbove--
+		}
+		if change.OldRating >= rating {
+			atOrAbove++
+		}
+		if atOrAbove > position {
+			best, found = rating, true
+		}
+	}
+	return best, found, nil
+}
+
+// percentiles reports whether any tier moves with the board
+func (t Table) percentiles() bool {
+	for _, tier := range t {
+		if tier.Percentile > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// TierFor returns the tier name for a rating
+func (s *Service) TierFor(ctx context.Context, rating int) (string, error) {
+	resolver, err := s.Resolver(ctx)
+	if err != nil {
+		return "", err
+	}
+	return resolver.TierFor(rating), nil
+}
+
+func (s *Service) handleRatingChange(ctx context.Context, change ranking.RatingChange) {
+	if change.OldRating == 0 || change.NewRating == 0 {
+		return
+	}
+
+	resolver, err := s.Resolver(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to resolve tiers for user %d: %v", change.UserID, err)
+		return
+	}
+	// Percentile thresholds moved with the write, so the old tier comes from
+	// the board as it was
+	before := resolver
+	if s.table.percentiles() {
+		if before, err = s.resolverBefore(ctx, change); err != nil {
+			log.Printf("Warning: failed to resolve previous tiers for user %d: %v", change.UserID, err)
+			return
+		}
+	}
+
+	oldIndex := before.indexFor(change.OldRating)
+	newIndex := resolver.indexFor(change.NewRating)
+	if oldIndex == newIndex {
+		return
+	}
+
+	eventType := events.TierPromoted
+	if newIndex < oldIndex {
+		eventType = events.TierDemoted
+	}
+
+	err = s.bus.Publish(ctx, events.Event{
+		Type:   eventType,
+		UserID: change.UserID,
+		Data: map[string]interface{}{
+			"from":   before.TierFor(change.OldRating),
+			"to":     resolver.TierFor(change.NewRating),
+			"rating": change.NewRating,
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: failed to publish tier event for user %d: %v", change.UserID, err)
+	}
+}
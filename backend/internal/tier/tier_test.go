@@ -0,0 +1,192 @@
+package tier
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/ranking"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Table
+		wantErr bool
+	}{
+		{spec: "", want: DefaultTable},
+		{
+			spec: "Bronze:100, Silver:1200,Master:top5%,Legend:top1%",
+			want: Table{
+				{Name: "Bronze", MinRating: 100},
+				{Name: "Silver", MinRating: 1200},
+				{Name: "Master", Percentile: 5},
+				{Name: "Legend", Percentile: 1},
+			},
+		},
+		{spec: "Gold:1500,Silver:1200", wantErr: true},
+		{spec: "Silver:1200,Gold:1200", wantErr: true},
+		{spec: "Bronze:100,Master:top1%,Legend:top5%", wantErr: true},
+		{spec: "Bronze:100,Master:top1%,Legend:top1%", wantErr: true},
+		{spec: "Bronze:100,Master:top1%,Legend:4900", wantErr: true},
+		{spec: "Master:top1%", wantErr: true},
+		{spec: "Bronze:100,bronze:200", wantErr: true},
+		{spec: "Bronze:100,Silver:top0%", wantErr: true},
+		{spec: "Bronze:100,Silver:top101%", wantErr: true},
+		{spec: "Bronze:abc", wantErr: true},
+		{spec: "Bronze", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTable(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseTable(%q) = %v, want error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTable(%q) error: %v", tt.spec, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseTable(%q) = %v, want %v", tt.spec, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParseTable(%q)[%d] = %v, want %v", tt.spec, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestResolverTierForAndBounds(t *testing.T) {
+	r := &Resolver{
+		names: []string{"Bronze", "Silver", "Gold"},
+		mins:  []int{100, 1200, 2000},
+	}
+
+	for rating, want := range map[int]string{50: "", 100: "Bronze", 1199: "Bronze", 1200: "Silver", 2500: "Gold"} {
+		if got := r.TierFor(rating); got != want {
+			t.Errorf("TierFor(%d) = %q, want %q", rating, got, want)
+		}
+	}
+
+	min, max, ok := r.Bounds("silver")
+	if !ok || min != 1200 || max != 1999 {
+		t.Errorf("Bounds(silver) = %d, %d, %v; want 1200, 1999, true", min, max, ok)
+	}
+	if _, _, ok := r.Bounds("Diamond"); ok {
+		t.Error("Bounds(Diamond) found an unknown tier")
+	}
+}
+
+func newTestService(t *testing.T, table Table) (*Service, *ranking.RankingService, *events.Bus) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	rankService := ranking.NewRankingService(client)
+	bus := events.NewBus(client)
+	return NewService(table, rankService, bus), rankService, bus
+}
+
+func TestResolverPercentileTier(t *testing.T) {
+	table := Table{{Name: "Bronze", MinRating: 100}, {Name: "Master", Percentile: 20}}
+	s, rankService, _ := newTestService(t, table)
+	ctx := context.Background()
+
+	// Ten players; the top 20% is the two best, plus anyone tied with the second
+	for i, rating := range []int{1000, 1100, 1200, 1300, 1400, 1500, 1600, 1700, 1800, 1800} {
+		if err := rankService.UpdateUserRating(ctx, int64(i+1), rating, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resolver, err := s.Resolver(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resolver.TierFor(1800); got != "Master" {
+		t.Errorf("TierFor(1800) = %q, want Master", got)
+	}
+	if got := resolver.TierFor(1700); got != "Bronze" {
+		t.Errorf("TierFor(1700) = %q, want Bronze", got)
+	}
+}
+
+func TestTierChangeEvents(t *testing.T) {
+	_, rankService, bus := newTestService(t, DefaultTable)
+	ctx := context.Background()
+
+	var got []events.Event
+	bus.Subscribe(func(ctx context.Context, event events.Event) {
+		got = append(got, event)
+	})
+
+	// Joining the board and moving within a tier publish nothing
+	for _, rating := range []int{1100, 1150} {
+		if err := rankService.UpdateUserRating(ctx, 1, rating, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 0 {
+		t.Fatalf("got %d events before crossing a tier, want 0", len(got))
+	}
+
+	if err := rankService.UpdateUserRating(ctx, 1, 2100, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := rankService.UpdateUserRating(ctx, 1, 1300, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	if got[0].Type != events.TierPromoted || got[0].Data["from"] != "Bronze" || got[0].Data["to"] != "Gold" {
+		t.Errorf("first event = %+v, want promotion from Bronze to Gold", got[0])
+	}
+	if got[1].Type != events.TierDemoted || got[1].Data["from"] != "Gold" || got[1].Data["to"] != "Silver" {
+		t.Errorf("second event = %+v, want demotion from Gold to Silver", got[1])
+	}
+}
+
+func TestPercentileTierEventsUseBoardBeforeChange(t *testing.T) {
+	table := Table{{Name: "Bronze", MinRating: 100}, {Name: "Master", Percentile: 40}}
+	_, rankService, bus := newTestService(t, table)
+	ctx := context.Background()
+
+	// Five players; Master is the top two
+	for i, rating := range []int{1500, 1400, 1300, 1200, 1100} {
+		if err := rankService.UpdateUserRating(ctx, int64(i+1), rating, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []events.Event
+	bus.Subscribe(func(ctx context.Context, event events.Event) {
+		got = append(got, event)
+	})
+
+	// The second player was already Master; overtaking the leader raises the
+	// threshold past their old rating but is no promotion
+	if err := rankService.UpdateUserRating(ctx, 2, 1600, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("got %+v, want no events for a move within Master", got)
+	}
+
+	// The third player passing the old leader is
+	if err := rankService.UpdateUserRating(ctx, 3, 1550, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Type != events.TierPromoted || got[0].UserID != 3 || got[0].Data["from"] != "Bronze" {
+		t.Errorf("got %+v, want user 3 promoted from Bronze", got)
+	}
+}
@@ -0,0 +1,180 @@
+package usercache
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/repository"
+)
+
+// entryTTL bounds how long a display entry outlives a missed invalidation
+const entryTTL = time.Hour
+
+// DisplayKey returns the hash holding a user's cached display fields under
+// "data", and under "gen" a counter that Invalidate bumps
+func DisplayKey(userID int64) string {
+	return "users:display:" + strconv.FormatInt(userID, 10)
+}
+
+// fillScript stores display data only if the entry has not been invalidated
+// since the caller read it, so a read that loaded the old username from
+// PostgreSQL before a rename can't put it back afterwards.
+// KEYS: entry. ARGV: generation read, encoded display, TTL in seconds.
+var fillScript = redis.NewScript(`
+local gen = tonumber(redis.call('HGET', KEYS[1], 'gen') or '0')
+if gen ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'data', ARGV[2])
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// Display is what a leaderboard row shows about a user besides their rating
+type Display struct {
+	Username string `json:"username"`
+	Region   string `json:"region"`
+}
+
+// Cache counters, published at /debug/vars as "user_display_cache"
+var (
+	hits   = new(expvar.Int)
+	misses = new(expvar.Int)
+	errs   = new(expvar.Int)
+)
+
+func init() {
+	stats := expvar.NewMap("user_display_cache")
+	stats.Set("hits", hits)
+	stats.Set("misses", misses)
+	stats.Set("errors", errs)
+	stats.Set("hit_rate", expvar.Func(func() interface{} {
+		total := hits.Value() + misses.Value()
+		if total == 0 {
+			return 0.0
+		}
+		return float64(hits.Value()) / float64(total)
+	}))
+}
+
+// Cache is a read-through cache of user display fields in Redis, so
+// leaderboard pages don't need PostgreSQL. Renames and deletions call
+// Invalidate once committed; entries also expire after entryTTL.
+type Cache struct {
+	redis    *redis.Client
+	userRepo *repository.UserRepository
+}
+
+func NewCache(redis *redis.Client, userRepo *repository.UserRepository) *Cache {
+	return &Cache{
+		redis:    redis,
+		userRepo: userRepo,
+	}
+}
+
+// Get returns display fields for the users that exist, loading misses from
+// PostgreSQL and caching them. If Redis is unavailable every user is read
+// from PostgreSQL.
+func (c *Cache) Get(ctx context.Context, userIDs []int64) (map[int64]Display, error) {
+	result := make(map[int64]Display, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	pipe := c.redis.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(userIDs))
+	for i, userID := range userIDs {
+		cmds[i] = pipe.HMGet(ctx, DisplayKey(userID), "data", "gen")
+	}
+
+	missing := userIDs
+	// gens holds each missing entry's generation as read, for the fill
+	var gens map[int64]string
+	if _, err := pipe.Exec(ctx); err != nil {
+		errs.Add(1)
+		log.Printf("Failed to read user display cache: %v", err)
+	} else {
+		missing = nil
+		gens = make(map[int64]string)
+		for i, cmd := range cmds {
+			values := cmd.Val()
+			var display Display
+			if raw, ok := values[0].(string); ok && json.Unmarshal([]byte(raw), &display) == nil {
+				result[userIDs[i]] = display
+				continue
+			}
+			missing = append(missing, userIDs[i])
+			gens[userIDs[i]] = "0"
+			if gen, ok := values[1].(string); ok {
+				gens[userIDs[i]] = gen
+			}
+		}
+	}
+	hits.Add(int64(len(userIDs) - len(missing)))
+	misses.Add(int64(len(missing)))
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	users, err := c.userRepo.GetByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		result[user.ID] = Display{Username: user.Username, Region: user.Region}
+	}
+
+	// Filling the cache is best effort; the page is served either way
+	if gens != nil && len(users) > 0 {
+		if err := c.fill(ctx, users, result, gens); err != nil {
+			errs.Add(1)
+			log.Printf("Failed to fill user display cache: %v", err)
+		}
+	}
+	return result, nil
+}
+
+func (c *Cache) fill(ctx context.Context, users []*models.User, displays map[int64]Display, gens map[int64]string) error {
+	// EVALSHA inside a pipeline can't fall back to EVAL, so load the script first
+	if err := fillScript.Load(ctx, c.redis).Err(); err != nil {
+		return err
+	}
+	ttl := int(entryTTL / time.Second)
+	pipe := c.redis.Pipeline()
+	for _, user := range users {
+		encoded, err := json.Marshal(displays[user.ID])
+		if err != nil {
+			return fmt.Errorf("failed to encode user display: %w", err)
+		}
+		fillScript.EvalSha(ctx, pipe, []string{DisplayKey(user.ID)}, gens[user.ID], encoded, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Invalidate drops cached display fields, to be called after a rename or
+// deletion has been committed to PostgreSQL. Reads already under way when it
+// runs can't refill the old fields.
+func (c *Cache) Invalidate(ctx context.Context, userIDs ...int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	pipe := c.redis.TxPipeline()
+	for _, userID := range userIDs {
+		key := DisplayKey(userID)
+		pipe.HDel(ctx, key, "data")
+		pipe.HIncrBy(ctx, key, "gen", 1)
+		pipe.Expire(ctx, key, entryTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to invalidate user display cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package usercache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"matkis-assignment/backend/internal/models"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func newTestCache(t *testing.T) (*Cache, *miniredis.Miniredis, sqlmock.Sqlmock) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return NewCache(client, repository.NewUserRepository(db)), server, mock
+}
+
+func userRows(users ...*models.User) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "rating", "region", "games_played", "provisional", "status", "version", "created_at", "updated_at",
+	})
+	for _, user := range users {
+		rows.AddRow(user.ID, user.Username, user.Rating, user.Region, 0, false, models.StatusActive, 1, time.Now(), time.Now())
+	}
+	return rows
+}
+
+func TestGetReadsThrough(t *testing.T) {
+	c, server, mock := newTestCache(t)
+	ctx := context.Background()
+
+	// User 3 doesn't exist and is left out
+	mock.ExpectQuery("FROM users").WillReturnRows(userRows(
+		&models.User{ID: 1, Username: "alice", Region: "DE"},
+		&models.User{ID: 2, Username: "bob"},
+	))
+	got, err := c.Get(ctx, []int64{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1] != (Display{Username: "alice", Region: "DE"}) || got[2].Username != "bob" {
+		t.Fatalf("Get = %+v, want alice and bob", got)
+	}
+	if ttl := server.TTL(DisplayKey(1)); ttl <= 0 || ttl > entryTTL {
+		t.Errorf("entry TTL = %v, want up to %v", ttl, entryTTL)
+	}
+
+	// Served from Redis: no further queries expected
+	got, err = c.Get(ctx, []int64{2, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].Username != "alice" {
+		t.Errorf("cached Get = %+v, want alice and bob", got)
+	}
+}
+
+func TestInvalidateBlocksStaleFill(t *testing.T) {
+	c, _, mock := newTestCache(t)
+	ctx := context.Background()
+
+	// A read loads the old username, then the rename commits and invalidates
+	// before the read gets to fill the cache
+	stale := []*models.User{{ID: 1, Username: "old"}}
+	if err := c.Invalidate(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.fill(ctx, stale, map[int64]Display{1: {Username: "old"}}, map[int64]string{1: "0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("FROM users").WillReturnRows(userRows(&models.User{ID: 1, Username: "new"}))
+	got, err := c.Get(ctx, []int64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[1].Username != "new" {
+		t.Fatalf("Get after rename = %q, want new", got[1].Username)
+	}
+
+	// The read that saw the current generation did fill it
+	got, err = c.Get(ctx, []int64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[1].Username != "new" {
+		t.Errorf("cached Get = %q, want new", got[1].Username)
+	}
+}
+
+func TestGetWithoutRedis(t *testing.T) {
+	c, server, mock := newTestCache(t)
+	server.Close()
+
+	mock.ExpectQuery("FROM users").WillReturnRows(userRows(&models.User{ID: 1, Username: "alice"}))
+	got, err := c.Get(context.Background(), []int64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[1].Username != "alice" {
+		t.Errorf("Get = %+v, want alice from PostgreSQL", got)
+	}
+}
@@ -0,0 +1,293 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	mrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"matkis-assignment/backend/internal/events"
+	"matkis-assignment/backend/internal/repository"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+	batchSize   = 50
+	concurrency = 8
+
+	// typesTTL is how long the set of subscribed event types is cached before
+	// events nobody wants are checked against the database again
+	typesTTL = 30 * time.Second
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside tolerance")
+)
+
+// Sign returns the signature header value for a delivery: an HMAC-SHA256 of
+// the timestamp, a dot and the body, keyed with the webhook's secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature, and that its timestamp is within
+// tolerance of now to stop replays. A zero tolerance skips the time check.
+func Verify(secret, timestamp string, body []byte, signature string, tolerance time.Duration) error {
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return ErrStaleTimestamp
+		}
+		age := time.Since(time.Unix(seconds, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrStaleTimestamp
+		}
+	}
+	return nil
+}
+
+// NewSecret generates a random signing secret
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// ValidateURL checks a webhook URL is an absolute http(s) URL
+func ValidateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// ValidateEventTypes checks every type can be subscribed to
+func ValidateEventTypes(types []string) error {
+	if len(types) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	for _, t := range types {
+		known := false
+		for _, eventType := range events.Types {
+			if string(eventType) == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	return nil
+}
+
+// Backoff returns the wait before retrying after the given number of failed
+// attempts: doubling from 10s up to an hour, with up to 10% jitter
+func Backoff(attempts int) time.Duration {
+	wait := maxBackoff
+	if attempts < 20 {
+		wait = baseBackoff << (attempts - 1)
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+	}
+	return wait + time.Duration(mrand.Int63n(int64(wait)/10+1))
+}
+
+// Dispatcher queues events for subscribed webhooks and delivers them. The
+// queue lives in PostgreSQL, so deliveries survive restarts and several
+// servers can run the dispatcher side by side.
+type Dispatcher struct {
+	repo        *repository.WebhookRepository
+	client      *http.Client
+	maxAttempts int
+	timeout     time.Duration
+
+	mu      sync.Mutex
+	types   map[string]bool
+	typesAt time.Time
+}
+
+func NewDispatcher(repo *repository.WebhookRepository, maxAttempts int, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		repo:        repo,
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: maxAttempts,
+		timeout:     timeout,
+	}
+}
+
+// HandleEvent is an events.Handler that queues the event for every webhook
+// subscribed to its type
+func (d *Dispatcher) HandleEvent(ctx context.Context, event events.Event) {
+	if !d.subscribed(ctx, string(event.Type)) {
+		return
+	}
+	if _, err := d.Enqueue(ctx, event, 0); err != nil {
+		log.Printf("Warning: failed to queue %s webhooks: %v", event.Type, err)
+	}
+}
+
+// Enqueue queues an event for the webhooks subscribed to its type, or for
+// webhookID alone if it is non-zero, returning how many deliveries were queued
+func (d *Dispatcher) Enqueue(ctx context.Context, event events.Event, webhookID int64) (int64, error) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+	return d.repo.Enqueue(ctx, string(event.Type), payload, webhookID)
+}
+
+// Refresh drops the cached set of subscribed event types, to be called after
+// webhooks change
+func (d *Dispatcher) Refresh() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.types = nil
+}
+
+func (d *Dispatcher) subscribed(ctx context.Context, eventType string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.types == nil || time.Since(d.typesAt) > typesTTL {
+		types, err := d.repo.SubscribedTypes(ctx)
+		if err != nil {
+			// Let the queue decide rather than drop the event
+			log.Printf("Warning: failed to load webhook subscriptions: %v", err)
+			return true
+		}
+		d.types, d.typesAt = types, time.Now()
+	}
+	return d.types[eventType]
+}
+
+// Run delivers due webhooks every interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.DeliverDue(ctx); err != nil {
+				log.Printf("Warning: failed to deliver webhooks: %v", err)
+			}
+		}
+	}
+}
+
+// DeliverDue sends queued deliveries that are due, batch by batch until none
+// are left
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		// The lease outlasts a send so a slow receiver isn't sent the same
+		// delivery twice
+		deliveries, err := d.repo.ClaimDue(ctx, batchSize, 2*d.timeout+time.Minute)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(delivery *repository.ClaimedDelivery) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				d.deliver(ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(deliveries) < batchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *repository.ClaimedDelivery) {
+	sendErr := d.send(ctx, delivery)
+	if sendErr == nil {
+		if err := d.repo.MarkDelivered(ctx, delivery.ID); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	dead := attempts >= d.maxAttempts
+	if dead {
+		log.Printf("Webhook delivery %d to %s failed %d times, moving to dead letters: %v", delivery.ID, delivery.URL, attempts, sendErr)
+	}
+	if err := d.repo.MarkFailed(ctx, delivery.ID, sendErr.Error(), Backoff(attempts), dead); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *repository.ClaimedDelivery) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "leaderboard-webhooks/1.0")
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("receiver returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return nil
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"matkis-assignment/backend/internal/repository"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"ping"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := Sign("whsec_test", now, body)
+
+	// Known answer, computed with openssl dgst -sha256 -hmac secret, so
+	// receivers in other languages can check theirs
+	want := "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := Sign("secret", "1700000000", []byte("{}")); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		signature string
+		tolerance time.Duration
+		want      error
+	}{
+		{name: "valid", secret: "whsec_test", timestamp: now, body: body, signature: signature, tolerance: 5 * time.Minute},
+		{name: "tampered body", secret: "whsec_test", timestamp: now, body: []byte(`{"type":"pong"}`), signature: signature, want: ErrInvalidSignature},
+		{name: "wrong secret", secret: "whsec_other", timestamp: now, body: body, signature: signature, want: ErrInvalidSignature},
+		{name: "timestamp changed", secret: "whsec_test", timestamp: "1", body: body, signature: signature, want: ErrInvalidSignature},
+		{
+			name: "replayed", secret: "whsec_test", timestamp: "1700000000", body: body,
+			signature: Sign("whsec_test", "1700000000", body), tolerance: 5 * time.Minute, want: ErrStaleTimestamp,
+		},
+		{
+			name: "no tolerance", secret: "whsec_test", timestamp: "1700000000", body: body,
+			signature: Sign("whsec_test", "1700000000", body),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.timestamp, tt.body, tt.signature, tt.tolerance); err != tt.want {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{5, 160 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := Backoff(tt.attempts); got < tt.base || got > tt.base+tt.base/10 {
+				t.Errorf("Backoff(%d) = %v, want %v plus up to 10%%", tt.attempts, got, tt.base)
+				break
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"https://example.com/hooks": true,
+		"http://localhost:9000":     true,
+		"ftp://example.com":         false,
+		"/hooks":                    false,
+		"https://":                  false,
+		"not a url":                 false,
+	} {
+		if err := ValidateURL(raw); (err == nil) != ok {
+			t.Errorf("ValidateURL(%q) = %v, want ok %v", raw, err, ok)
+		}
+	}
+
+	if err := ValidateEventTypes([]string{"rank.overtaken", "tier.promoted"}); err != nil {
+		t.Errorf("ValidateEventTypes: %v", err)
+	}
+	if err := ValidateEventTypes(nil); err == nil {
+		t.Error("ValidateEventTypes accepted no types")
+	}
+	if err := ValidateEventTypes([]string{"rank.overtaken", "user.deleted"}); err == nil {
+		t.Error("ValidateEventTypes accepted an unknown type")
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Deliveries are sent concurrently
+	mock.MatchExpectationsInOrder(false)
+
+	var mu sync.Mutex
+	var verifyErr error
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		verifyErr = Verify("whsec_a", r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature), time.Minute)
+		mu.Unlock()
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("UPDATE webhook_deliveries d").WillReturnRows(sqlmock.NewRows([]string{
+		"id", "webhook_id", "event_type", "payload", "status", "attempts", "next_attempt_at",
+		"last_error", "created_at", "delivered_at", "url", "secret",
+	}).
+		AddRow(1, 1, "ping", []byte(`{"type":"ping"}`), "pending", 0, now, nil, now, nil, ok.URL, "whsec_a").
+		AddRow(2, 2, "ping", []byte(`{"type":"ping"}`), "pending", 4, now, nil, now, nil, failing.URL, "whsec_b"))
+	mock.ExpectExec("SET status = 'delivered'").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	// The fifth failure moves the delivery to the dead letters
+	mock.ExpectExec("SET status = \\$1").WithArgs("dead", sqlmock.AnyArg(), sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	d := NewDispatcher(repository.NewWebhookRepository(db), 5, time.Second)
+	if err := d.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if verifyErr != nil {
+		t.Errorf("receiver could not verify the delivery: %v", verifyErr)
+	}
+}
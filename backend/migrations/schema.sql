@@ -24,3 +24,152 @@ $$ language 'plpgsql';
 -- Create trigger to automatically update updated_at
 CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Region (ISO 3166-1 alpha-2 country code) for regional leaderboards
+ALTER TABLE users ADD COLUMN IF NOT EXISTS region VARCHAR(2) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_users_region_rating ON users(region, rating DESC);
+
+-- Follow relation between users, used for friends leaderboards
+CREATE TABLE IF NOT EXISTS friendships (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    friend_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, friend_id),
+    CHECK (user_id <> friend_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_friendships_friend_id ON friendships(friend_id);
+
+-- Teams (clans) and their members; a user belongs to at most one team
+CREATE TABLE IF NOT EXISTS teams (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id BIGINT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (team_id, user_id)
+);
+
+-- Log of every rating written to the leaderboard, replayed on top of
+-- snapshots to reconstruct historical standings
+CREATE TABLE IF NOT EXISTS rating_changes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_rating INTEGER,
+    new_rating INTEGER,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rating_changes_changed_at ON rating_changes(changed_at);
+CREATE INDEX IF NOT EXISTS idx_rating_changes_user_id ON rating_changes(user_id, changed_at);
+
+-- Periodic compact snapshots of the global leaderboard (varint-encoded user ID/rating pairs).
+-- Blobs are never rewritten, so they keep deleted users; readers drop users with no row.
+CREATE TABLE IF NOT EXISTS leaderboard_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    taken_at TIMESTAMP NOT NULL,
+    user_count INTEGER NOT NULL,
+    data BYTEA NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_leaderboard_snapshots_taken_at ON leaderboard_snapshots(taken_at);
+
+-- Daily per-user rank snapshots for rank history and movers
+CREATE TABLE IF NOT EXISTS rank_snapshots (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    snapshot_date DATE NOT NULL,
+    rank INTEGER NOT NULL,
+    rating INTEGER NOT NULL,
+    PRIMARY KEY (user_id, snapshot_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rank_snapshots_date ON rank_snapshots(snapshot_date);
+
+-- Match results between two players (winner_id is NULL for a draw)
+CREATE TABLE IF NOT EXISTS matches (
+    id BIGSERIAL PRIMARY KEY,
+    player_a BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    player_b BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    winner_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    played_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (player_a <> player_b)
+);
+
+CREATE INDEX IF NOT EXISTS idx_matches_player_a ON matches(player_a, played_at DESC);
+CREATE INDEX IF NOT EXISTS idx_matches_player_b ON matches(player_b, played_at DESC);
+
+-- Provisional players are kept off the public leaderboard until they have
+-- played enough rated games; existing users default to established
+ALTER TABLE users ADD COLUMN IF NOT EXISTS games_played INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS provisional BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Moderation state: hidden and shadow-banned users are kept off the public
+-- leaderboard, banned users additionally can't receive rating updates
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'hidden', 'banned', 'shadow_banned'));
+
+CREATE INDEX IF NOT EXISTS idx_users_status ON users(status) WHERE status <> 'active';
+
+-- Rating updates held back by the anti-cheat checks until an admin reviews them
+CREATE TABLE IF NOT EXISTS rating_reviews (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_rating INTEGER NOT NULL,
+    new_rating INTEGER NOT NULL,
+    reasons TEXT[] NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rating_reviews_pending ON rating_reviews(created_at) WHERE status = 'pending';
+
+-- Username changes, kept for the user's data export
+CREATE TABLE IF NOT EXISTS username_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_username VARCHAR(255) NOT NULL,
+    new_username VARCHAR(255) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_user_id ON username_history(user_id, changed_at);
+
+-- Row version for optimistic concurrency on rating updates
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- User version a held update was made against, checked when it is approved;
+-- 0 for reviews held before versions were recorded
+ALTER TABLE rating_reviews ADD COLUMN IF NOT EXISTS user_version BIGINT NOT NULL DEFAULT 0;
+
+-- Webhook subscriptions to leaderboard events
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Outgoing webhook deliveries. Pending rows are the delivery queue; rows
+-- that run out of attempts stay behind as dead letters.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at);
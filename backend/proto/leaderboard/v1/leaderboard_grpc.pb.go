@@ -0,0 +1,301 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: leaderboard/v1/leaderboard.proto
+
+package leaderboardv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	LeaderboardService_GetLeaderboard_FullMethodName = "/leaderboard.v1.LeaderboardService/GetLeaderboard"
+	LeaderboardService_GetRank_FullMethodName        = "/leaderboard.v1.LeaderboardService/GetRank"
+	LeaderboardService_SearchUsers_FullMethodName    = "/leaderboard.v1.LeaderboardService/SearchUsers"
+	LeaderboardService_UpdateRating_FullMethodName   = "/leaderboard.v1.LeaderboardService/UpdateRating"
+	LeaderboardService_WatchRanks_FullMethodName     = "/leaderboard.v1.LeaderboardService/WatchRanks"
+)
+
+// LeaderboardServiceClient is the client API for LeaderboardService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LeaderboardServiceClient interface {
+	// GetLeaderboard returns a page of the global or a regional leaderboard,
+	// optionally limited to a rating range.
+	GetLeaderboard(ctx context.Context, in *GetLeaderboardRequest, opts ...grpc.CallOption) (*GetLeaderboardResponse, error)
+	// GetRank returns a user's global and regional rank.
+	GetRank(ctx context.Context, in *GetRankRequest, opts ...grpc.CallOption) (*GetRankResponse, error)
+	// SearchUsers finds users by username prefix.
+	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*SearchUsersResponse, error)
+	// UpdateRating sets a user's rating, running the same anti-cheat checks as
+	// the REST API. Suspicious updates are held for review rather than applied.
+	UpdateRating(ctx context.Context, in *UpdateRatingRequest, opts ...grpc.CallOption) (*UpdateRatingResponse, error)
+	// WatchRanks streams rank changes, either for a set of users or for every
+	// change to the global leaderboard.
+	WatchRanks(ctx context.Context, in *WatchRanksRequest, opts ...grpc.CallOption) (LeaderboardService_WatchRanksClient, error)
+}
+
+type leaderboardServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLeaderboardServiceClient(cc grpc.ClientConnInterface) LeaderboardServiceClient {
+	return &leaderboardServiceClient{cc}
+}
+
+func (c *leaderboardServiceClient) GetLeaderboard(ctx context.Context, in *GetLeaderboardRequest, opts ...grpc.CallOption) (*GetLeaderboardResponse, error) {
+	out := new(GetLeaderboardResponse)
+	err := c.cc.Invoke(ctx, LeaderboardService_GetLeaderboard_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaderboardServiceClient) GetRank(ctx context.Context, in *GetRankRequest, opts ...grpc.CallOption) (*GetRankResponse, error) {
+	out := new(GetRankResponse)
+	err := c.cc.Invoke(ctx, LeaderboardService_GetRank_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaderboardServiceClient) SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*SearchUsersResponse, error) {
+	out := new(SearchUsersResponse)
+	err := c.cc.Invoke(ctx, LeaderboardService_SearchUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaderboardServiceClient) UpdateRating(ctx context.Context, in *UpdateRatingRequest, opts ...grpc.CallOption) (*UpdateRatingResponse, error) {
+	out := new(UpdateRatingResponse)
+	err := c.cc.Invoke(ctx, LeaderboardService_UpdateRating_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaderboardServiceClient) WatchRanks(ctx context.Context, in *WatchRanksRequest, opts ...grpc.CallOption) (LeaderboardService_WatchRanksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LeaderboardService_ServiceDesc.Streams[0], LeaderboardService_WatchRanks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &leaderboardServiceWatchRanksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type LeaderboardService_WatchRanksClient interface {
+	Recv() (*WatchRanksResponse, error)
+	grpc.ClientStream
+}
+
+type leaderboardServiceWatchRanksClient struct {
+	grpc.ClientStream
+}
+
+func (x *leaderboardServiceWatchRanksClient) Recv() (*WatchRanksResponse, error) {
+	m := new(WatchRanksResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LeaderboardServiceServer is the server API for LeaderboardService service.
+// All implementations must embed UnimplementedLeaderboardServiceServer
+// for forward compatibility
+type LeaderboardServiceServer interface {
+	// GetLeaderboard returns a page of the global or a regional leaderboard,
+	// optionally limited to a rating range.
+	GetLeaderboard(context.Context, *GetLeaderboardRequest) (*GetLeaderboardResponse, error)
+	// GetRank returns a user's global and regional rank.
+	GetRank(context.Context, *GetRankRequest) (*GetRankResponse, error)
+	// SearchUsers finds users by username prefix.
+	SearchUsers(context.Context, *SearchUsersRequest) (*SearchUsersResponse, error)
+	// UpdateRating sets a user's rating, running the same anti-cheat checks as
+	// the REST API. Suspicious updates are held for review rather than applied.
+	UpdateRating(context.Context, *UpdateRatingRequest) (*UpdateRatingResponse, error)
+	// WatchRanks streams rank changes, either for a set of users or for every
+	// change to the global leaderboard.
+	WatchRanks(*WatchRanksRequest, LeaderboardService_WatchRanksServer) error
+	mustEmbedUnimplementedLeaderboardServiceServer()
+}
+
+// UnimplementedLeaderboardServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedLeaderboardServiceServer struct {
+}
+
+func (UnimplementedLeaderboardServiceServer) GetLeaderboard(context.Context, *GetLeaderboardRequest) (*GetLeaderboardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLeaderboard not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) GetRank(context.Context, *GetRankRequest) (*GetRankResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRank not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) SearchUsers(context.Context, *SearchUsersRequest) (*SearchUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchUsers not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) UpdateRating(context.Context, *UpdateRatingRequest) (*UpdateRatingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRating not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) WatchRanks(*WatchRanksRequest, LeaderboardService_WatchRanksServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRanks not implemented")
+}
+func (UnimplementedLeaderboardServiceServer) mustEmbedUnimplementedLeaderboardServiceServer() {}
+
+// UnsafeLeaderboardServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LeaderboardServiceServer will
+// result in compilation errors.
+type UnsafeLeaderboardServiceServer interface {
+	mustEmbedUnimplementedLeaderboardServiceServer()
+}
+
+func RegisterLeaderboardServiceServer(s grpc.ServiceRegistrar, srv LeaderboardServiceServer) {
+	s.RegisterService(&LeaderboardService_ServiceDesc, srv)
+}
+
+func _LeaderboardService_GetLeaderboard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLeaderboardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaderboardServiceServer).GetLeaderboard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaderboardService_GetLeaderboard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaderboardServiceServer).GetLeaderboard(ctx, req.(*GetLeaderboardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaderboardService_GetRank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRankRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaderboardServiceServer).GetRank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaderboardService_GetRank_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaderboardServiceServer).GetRank(ctx, req.(*GetRankRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaderboardService_SearchUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaderboardServiceServer).SearchUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaderboardService_SearchUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaderboardServiceServer).SearchUsers(ctx, req.(*SearchUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaderboardService_UpdateRating_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRatingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaderboardServiceServer).UpdateRating(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaderboardService_UpdateRating_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaderboardServiceServer).UpdateRating(ctx, req.(*UpdateRatingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaderboardService_WatchRanks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRanksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LeaderboardServiceServer).WatchRanks(m, &leaderboardServiceWatchRanksServer{stream})
+}
+
+type LeaderboardService_WatchRanksServer interface {
+	Send(*WatchRanksResponse) error
+	grpc.ServerStream
+}
+
+type leaderboardServiceWatchRanksServer struct {
+	grpc.ServerStream
+}
+
+func (x *leaderboardServiceWatchRanksServer) Send(m *WatchRanksResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// LeaderboardService_ServiceDesc is the grpc.ServiceDesc for LeaderboardService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LeaderboardService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "leaderboard.v1.LeaderboardService",
+	HandlerType: (*LeaderboardServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLeaderboard",
+			Handler:    _LeaderboardService_GetLeaderboard_Handler,
+		},
+		{
+			MethodName: "GetRank",
+			Handler:    _LeaderboardService_GetRank_Handler,
+		},
+		{
+			MethodName: "SearchUsers",
+			Handler:    _LeaderboardService_SearchUsers_Handler,
+		},
+		{
+			MethodName: "UpdateRating",
+			Handler:    _LeaderboardService_UpdateRating_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRanks",
+			Handler:       _LeaderboardService_WatchRanks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "leaderboard/v1/leaderboard.proto",
+}
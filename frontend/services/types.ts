@@ -3,12 +3,17 @@ export interface LeaderboardEntry {
   username: string;
   rating: number;
   user_id: number;
+  region: string;
+  tier: string;
 }
 
 export interface SearchResult {
   global_rank: number;
+  regional_rank: number;
   username: string;
   rating: number;
+  region: string;
+  tier: string;
 }
 
 export interface LeaderboardResponse {